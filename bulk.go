@@ -0,0 +1,180 @@
+package mworm
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// DefaultBulkSize INSERTMany 每条语句默认的最大行数
+var DefaultBulkSize = 1000
+
+// INSERTMany 批量插入，rows 为结构体或结构体指针的切片
+func INSERTMany(rows any) *OrmModel {
+	return defaultDB().INSERTMany(rows)
+}
+
+// INSERTMany 批量插入，生成 INSERT ... VALUES (...),(...) 语句
+//
+// 按 BulkSize 及方言的参数数量上限拆分为多条语句，多条语句时在事务中执行，rows 为空时不执行任何语句
+func (d *DB) INSERTMany(rows any) *OrmModel {
+	list, entity, err := bulkRows(rows)
+	if err != nil {
+		o := &OrmModel{db: d, method: methodInsert}
+		o.init()
+		o.err = err
+		return o
+	}
+	o := d.INSERT(entity)
+	o.bulkRows = list
+	return o
+}
+
+// INSERTMany 在事务中批量插入
+func (tx *Tx) INSERTMany(rows any) *OrmModel {
+	o := tx.db.INSERTMany(rows)
+	o.tx, o.ctx = tx, tx.ctx
+	return o
+}
+
+// BulkSize 设置 INSERTMany 每条语句的最大行数
+func (o *OrmModel) BulkSize(rows int) *OrmModel {
+	o.bulkSize = rows
+	return o
+}
+
+// bulkRows 展开切片，返回数据行及用于获取表名的元素零值
+func bulkRows(rows any) ([]any, ORMInterface, error) {
+	v := reflect.Indirect(reflect.ValueOf(rows))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, nil, fmt.Errorf("INSERTMany: rows must be a slice, got %T", rows)
+	}
+	entity, err := elemEntity(v.Type().Elem())
+	if err != nil {
+		return nil, nil, err
+	}
+	list := make([]any, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		e := v.Index(i)
+		if e.Kind() == reflect.Ptr {
+			if e.IsNil() {
+				continue
+			}
+			e = e.Elem()
+		}
+		list = append(list, e.Interface())
+	}
+	return list, entity, nil
+}
+
+// elemEntity 返回元素类型 t 的零值，用于获取表名及字段
+func elemEntity(t reflect.Type) (ORMInterface, error) {
+	zero := reflect.New(reflectx.Deref(t))
+	if entity, ok := zero.Elem().Interface().(ORMInterface); ok {
+		return entity, nil
+	}
+	if entity, ok := zero.Interface().(ORMInterface); ok {
+		return entity, nil
+	}
+	return nil, fmt.Errorf("%s must implement ORMInterface", zero.Elem().Type())
+}
+
+// BuildBulkSQL 构造 INSERTMany 的全部语句
+//
+// 每行的列与 INSERT 相同，空值字段按 db tag 及 AllowEmpty 跳过，列相同的相邻行合并到同一条语句，语句顺序与 rows 一致
+func (o *OrmModel) BuildBulkSQL() ([]SQLParams, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	type bulkGroup struct {
+		fields []string
+		rows   [][]any
+	}
+	var groups []*bulkGroup
+	for _, row := range o.bulkRows {
+		r := &OrmModel{db: o.db, method: methodInsert}
+		r.init()
+		r.structToMap(row)
+		for k := range o.emptyUpdateFields {
+			r.emptyUpdateFields[k] = emptyKey{}
+		}
+		r.excludeFields, r.requiredFields = o.excludeFields, o.requiredFields
+		fields, values := r.insertValues(r.filterParams(r.params))
+		if len(fields) == 0 {
+			continue
+		}
+		// 列与上一行不同时开始新的语句，保持插入顺序
+		if len(groups) == 0 || !slices.Equal(groups[len(groups)-1].fields, fields) {
+			groups = append(groups, &bulkGroup{fields: fields})
+		}
+		g := groups[len(groups)-1]
+		g.rows = append(g.rows, values)
+	}
+
+	size := o.bulkSize
+	if size < 1 {
+		size = DefaultBulkSize
+	}
+	maxArgs := o.dialect().MaxPlaceholders()
+	var sqls []SQLParams
+	for _, g := range groups {
+		n := size
+		if maxArgs > 0 && maxArgs/len(g.fields) < n {
+			n = max(maxArgs/len(g.fields), 1)
+		}
+		for start := 0; start < len(g.rows); start += n {
+			o.args = nil
+			values := make([]string, 0, n)
+			for _, row := range g.rows[start:min(start+n, len(g.rows))] {
+				names := make([]string, 0, len(row))
+				for _, arg := range row {
+					names = append(names, o.bindArg(arg))
+				}
+				values = append(values, `(`+strings.Join(names, `, `)+`)`)
+			}
+			sql := fmt.Sprintf(`%s %s (%s) VALUES %s%s`, `INSERT INTO`, o.tableName, strings.Join(g.fields, `, `),
+				strings.Join(values, `, `), o.upsertSQL(g.fields))
+			sqls = append(sqls, SQLParams{Sql: sql, Args: o.args})
+		}
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	return sqls, nil
+}
+
+// execBulk 执行 INSERTMany，多条语句且不在事务中时开启事务
+func (o *OrmModel) execBulk(e sqlx.ExtContext) (int64, error) {
+	sqls, err := o.BuildBulkSQL()
+	if err != nil {
+		return 0, err
+	}
+	if len(sqls) == 0 {
+		// 没有需要插入的行
+		return 0, nil
+	}
+	run := func(e sqlx.ExtContext) (count int64, err error) {
+		for _, sp := range sqls {
+			n, err := execContext(o.context(), e, sp.Sql, sp.Args...)
+			count += n
+			if err != nil {
+				return count, err
+			}
+		}
+		return count, nil
+	}
+	if len(sqls) == 1 || o.tx != nil {
+		return run(e)
+	}
+	var count int64
+	err = o.database().BatchFuncContext(o.context(), nil, func(tx *Tx) error {
+		var err error
+		count, err = run(o.intercept(tx.sqlxTx))
+		return err
+	})
+	return count, err
+}
@@ -0,0 +1,80 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+// smallDialect 限制参数数量，用于测试拆分语句
+type smallDialect struct{ PostgresDialect }
+
+func (smallDialect) MaxPlaceholders() int { return 4 }
+
+func TestINSERTMany(t *testing.T) {
+	db, f := newFakeDB(t)
+	rows := []*TestTable{{Name: "a", Type: 1}, {Name: "b", Type: 2}, nil, {Name: "c", Type: 3}, {Name: "d"}}
+	if err := db.INSERTMany(rows).BulkSize(2).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN",
+		`INSERT INTO "test_table" (name, type) VALUES ($1, $2), ($3, $4)`,
+		`INSERT INTO "test_table" (name, type) VALUES ($1, $2)`,
+		`INSERT INTO "test_table" (name) VALUES ($1)`,
+		"COMMIT",
+	}
+	if got := f.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements = %q", got)
+	}
+	if want := []driver.Value{"a", int64(1), "b", int64(2)}; !reflect.DeepEqual(f.args[1], want) {
+		t.Fatalf("args = %v", f.args[1])
+	}
+
+	sqls, err := db.INSERTMany([]TestTable{{ID: 1, Name: "a", Type: 1}, {ID: 2, Name: "b", Type: 2}}).
+		ExcludeFields("id").BuildBulkSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqls) != 1 || sqls[0].Sql != `INSERT INTO "test_table" (name, type) VALUES ($1, $2), ($3, $4)` {
+		t.Fatalf("sqls = %+v", sqls)
+	}
+
+	db.SetDialect(smallDialect{})
+	sqls, _ = db.INSERTMany([]TestTable{{Name: "a", Type: 1}, {Name: "b", Type: 2}, {Name: "c", Type: 3}}).
+		BuildBulkSQL()
+	if len(sqls) != 2 || len(sqls[0].Args) != 4 || len(sqls[1].Args) != 2 {
+		t.Fatalf("sqls = %+v", sqls)
+	}
+
+	if err := db.INSERTMany(TestTable{}).Exec(); err == nil {
+		t.Fatal("non-slice rows should return error")
+	}
+	n := len(f.statements())
+	if err := db.INSERTMany([]TestTable{}).Exec(); err != nil {
+		t.Fatalf("empty rows err = %v", err)
+	}
+	if err := db.INSERTMany([]*TestTable{nil}).Exec(); err != nil {
+		t.Fatalf("nil rows err = %v", err)
+	}
+	if got := f.statements(); len(got) != n {
+		t.Fatalf("empty rows executed %q", got[n:])
+	}
+}
+
+func TestINSERTManyOrder(t *testing.T) {
+	db, _ := newFakeDB(t)
+	rows := []TestTable{{Name: "a", Type: 1}, {Name: "b"}, {Name: "c", Type: 3}, {Name: "d", Type: 4}}
+	sqls, err := db.INSERTMany(rows).BuildBulkSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SQLParams{
+		{Sql: `INSERT INTO "test_table" (name, type) VALUES ($1, $2)`, Args: []any{"a", 1}},
+		{Sql: `INSERT INTO "test_table" (name) VALUES ($1)`, Args: []any{"b"}},
+		{Sql: `INSERT INTO "test_table" (name, type) VALUES ($1, $2), ($3, $4)`, Args: []any{"c", 3, "d", 4}},
+	}
+	if !reflect.DeepEqual(sqls, want) {
+		t.Fatalf("sqls = %+v", sqls)
+	}
+}
@@ -1,10 +1,13 @@
 package mworm
 
 import (
+	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 )
@@ -32,6 +35,7 @@ const (
 	cgTypeSymbol                                    // cgTypeSymbol: 符号条件
 	cgTypeRaw                                       // cgTypeRaw: 原始条件
 	cgTypeGroupFields                               // cgTypeGroupFields: 分组字段
+	cgTypeJoinOn                                    // cgTypeJoinOn: JOIN ON 字段条件
 	cgAutoFill            = 99                      // cgAutoFill: 自动填充
 	cgAutoFillZero        = 100                     // cgAutoFillZero: 自动填充零值
 )
@@ -42,7 +46,7 @@ type ConditionGroup struct {
 	Symbol   string        // Symbol: 比较符号（=, >, < 等）
 	JsonTags []string      // JsonTags: 参与条件的字段名
 	Args     []any         // Args: 参数值
-	InArgs   []string      // InArgs: IN 查询参数
+	InArgs   []string      // InArgs: 已弃用，IN 查询参数改由 Args 绑定
 	Express  string        // Express: 表达式
 	cType    ConditionType // cType: 条件类型
 }
@@ -84,24 +88,13 @@ func Or2F(tag string, args ...any) ConditionGroup {
 
 // IN 构造 IN 查询条件分组
 func IN[T int | string](tag string, args ...T) ConditionGroup {
-	var result []string
-	if len(args) > 0 {
-		i := args[0]
-		t := reflect.TypeOf(i)
-		switch t.Kind() {
-		case reflect.String:
-			for _, arg := range args {
-				result = append(result, fmt.Sprintf(`'%v'`, arg))
-			}
-		default:
-			for _, arg := range args {
-				result = append(result, fmt.Sprintf(`%v`, arg))
-			}
-		}
+	result := make([]any, 0, len(args))
+	for _, arg := range args {
+		result = append(result, arg)
 	}
 	return ConditionGroup{
 		JsonTags: []string{tag},
-		InArgs:   result,
+		Args:     result,
 		cType:    cgTypeIn,
 	}
 }
@@ -111,7 +104,7 @@ func Exp(express string, args ...any) ConditionGroup {
 	return ConditionGroup{Express: express, Args: args, cType: cgTypeNamedExpress}
 }
 
-// Raw 条件表达式 column1 = 2 AND column2 = 'abc' 或 column1 = $1 AND column2 = $2
+// Raw 条件表达式 column1 = 2 AND column2 = 'abc'、column1 = $1 AND column2 = $2 或 column1 = ? AND column2 = ?
 func Raw(express string, args ...any) ConditionGroup {
 	return ConditionGroup{Express: express, Args: args, cType: cgTypeRaw}
 }
@@ -268,10 +261,8 @@ func Fields(tag ...string) ConditionGroup {
 func (o *OrmModel) parseConditionNamed() string {
 	var conditionSQL string
 	var groupArr []string
-	if len(o.namedCGArr) == 0 {
-		return ""
-	}
-	for _, cg := range o.namedCGArr {
+	for _, key := range o.namedCGKeys {
+		cg := o.namedCGArr[key]
 		switch cg.cType {
 		case cgTypeAndOr, cgTypeNull, cgTypeLike, cgTypeNotEqualLike, cgTypeNotEqualNull, cgTypeAndOrAutoRemove:
 			var names []string
@@ -281,13 +272,14 @@ func (o *OrmModel) parseConditionNamed() string {
 					continue
 				}
 				jv := o.params[column]
+				column = o.qualify(column)
 				switch cg.cType {
 				case cgTypeAndOr, cgTypeAndOrAutoRemove:
-					vStr := ValueTypeToStr(jv)
-					if (vStr == `` || vStr == `''` || vStr == `0`) && cg.cType == cgTypeAndOrAutoRemove {
+					arg, _ := valueToArg(jv)
+					if isZeroArg(arg) && cg.cType == cgTypeAndOrAutoRemove {
 						continue
 					}
-					names = append(names, fmt.Sprintf(`%s=%s`, column, vStr))
+					names = append(names, fmt.Sprintf(`%s=%s`, column, o.bindArg(arg)))
 				case cgTypeNull:
 					names = append(names, fmt.Sprintf(`%s IS NULL`, column))
 				case cgTypeNotEqualNull:
@@ -295,12 +287,12 @@ func (o *OrmModel) parseConditionNamed() string {
 				case cgTypeLike:
 					str, b := jv.(string)
 					if b && len(str) > 0 {
-						names = append(names, fmt.Sprintf(`%s LIKE '%%%s%%'`, column, jv))
+						names = append(names, fmt.Sprintf(`%s LIKE %s`, column, o.bindArg(`%`+str+`%`)))
 					}
 				case cgTypeNotEqualLike:
 					str, b := jv.(string)
 					if b && len(str) > 0 {
-						names = append(names, fmt.Sprintf(`%s NOT LIKE '%%%s%%'`, column, jv))
+						names = append(names, fmt.Sprintf(`%s NOT LIKE %s`, column, o.bindArg(`%`+str+`%`)))
 					}
 				default:
 				}
@@ -310,21 +302,30 @@ func (o *OrmModel) parseConditionNamed() string {
 				groupArr = append(groupArr, conditionStr)
 			}
 		case cgTypeOr2F, cgTypeAnd2F:
-			column := o.columnField(cg.JsonTags[0])
+			column := o.qualify(o.columnField(cg.JsonTags[0]))
 			if column == "" {
 				continue
 			}
 			var names []string
 			for _, arg := range cg.Args {
-				names = append(names, fmt.Sprintf(`%s=%s`, column, ValueTypeToStr(arg)))
+				v, _ := valueToArg(arg)
+				names = append(names, fmt.Sprintf(`%s=%s`, column, o.bindArg(v)))
 			}
 			if len(names) > 0 {
 				conditionStr := `(` + strings.Join(names, cg.Logic) + `)`
 				groupArr = append(groupArr, conditionStr)
 			}
 		case cgTypeIn: // IN
-			column := o.columnField(cg.JsonTags[0])
-			conditionStr := fmt.Sprintf(`%s IN (%s)`, column, strings.Join(cg.InArgs, ","))
+			column := o.qualify(o.columnField(cg.JsonTags[0]))
+			if len(cg.Args) == 0 {
+				groupArr = append(groupArr, `1=0`)
+				continue
+			}
+			names := make([]string, 0, len(cg.Args))
+			for _, arg := range cg.Args {
+				names = append(names, o.bindArg(arg))
+			}
+			conditionStr := fmt.Sprintf(`%s IN (%s)`, column, strings.Join(names, ","))
 			groupArr = append(groupArr, conditionStr)
 		case cgTypeNamedExpress: //表达式
 			//db_column1=:name1 OR db_column2=:name2
@@ -341,7 +342,8 @@ func (o *OrmModel) parseConditionNamed() string {
 				}
 				if len(keys) > 0 && len(keys) <= len(cg.Args) {
 					for i, key := range keys {
-						cg.Express = strings.Replace(cg.Express, ":"+key, ValueTypeToStr(cg.Args[i]), 1)
+						v, _ := valueToArg(cg.Args[i])
+						cg.Express = strings.Replace(cg.Express, ":"+key, o.bindArg(v), 1)
 					}
 				}
 			}
@@ -351,17 +353,8 @@ func (o *OrmModel) parseConditionNamed() string {
 			if cg.Express == "" {
 				continue
 			}
-			if len(cg.Args) == 0 {
-				conditionStr := `(` + cg.Express + `)`
-				groupArr = append(groupArr, conditionStr)
-			} else {
-				conditionStr := `(` + cg.Express + `)`
-				for i, arg := range cg.Args {
-					vStr := ValueTypeToStr(arg)
-					conditionStr = strings.Replace(conditionStr, "$"+strconv.Itoa(i+1), vStr, 1)
-				}
-				groupArr = append(groupArr, conditionStr)
-			}
+			conditionStr := `(` + o.bindRaw(cg.Express, cg.Args) + `)`
+			groupArr = append(groupArr, conditionStr)
 		case cgTypeAsc:
 			column := o.columnField(cg.JsonTags[0])
 			if len(column) > 0 {
@@ -377,31 +370,32 @@ func (o *OrmModel) parseConditionNamed() string {
 			if column == "" {
 				continue
 			}
-			var vStr string
+			var arg any
 			if len(cg.Args) > 0 {
-				vStr = ValueTypeToStr(cg.Args[0])
+				arg, _ = valueToArg(cg.Args[0])
 			} else {
-				vStr = ValueTypeToStr(o.params[column])
+				arg, _ = valueToArg(o.params[column])
 			}
-			if vStr == "" || vStr == `''` {
+			if isBlankArg(arg) {
 				continue
 			}
-			condition := fmt.Sprintf("%s%s%s", column, cg.Symbol, vStr)
+			condition := fmt.Sprintf("%s%s%s", o.qualify(column), cg.Symbol, o.bindArg(arg))
 			groupArr = append(groupArr, condition)
 		case cgAutoFill, cgAutoFillZero:
 			var conditionArr []string
-			for _, column := range o.dbFields {
+			for _, j := range orderedKeys(o.fieldOrder, o.dbFields) {
+				column := o.dbFields[j]
 				if len(column) == 0 {
 					continue
 				}
-				vStr := ValueTypeToStr(o.params[column])
-				if cg.cType == cgAutoFill && (vStr == "" || vStr == `''` || vStr == `0`) {
+				arg, ok := valueToArg(o.params[column])
+				if cg.cType == cgAutoFill && isZeroArg(arg) {
 					continue
 				}
-				if vStr == "" && cg.cType == cgAutoFillZero {
+				if !ok && cg.cType == cgAutoFillZero {
 					continue
 				}
-				conditionArr = append(conditionArr, fmt.Sprintf(`%s=%v`, column, vStr))
+				conditionArr = append(conditionArr, fmt.Sprintf(`%s=%s`, o.qualify(column), o.bindArg(arg)))
 			}
 			if len(conditionArr) > 0 {
 				conditionStr := `(` + strings.Join(conditionArr, ` AND `) + `)`
@@ -410,31 +404,198 @@ func (o *OrmModel) parseConditionNamed() string {
 		default:
 		}
 	}
+	if column := o.softDeleteColumn(); len(column) > 0 {
+		// 排除已软删除的记录
+		groupArr = append(groupArr, o.qualify(column)+` IS NULL`)
+	}
 	if len(groupArr) > 0 {
 		conditionSQL = ` WHERE ` + strings.Join(groupArr, and)
 	}
 	return conditionSQL
 }
 
+// rawPlaceholder 原始表达式中的 $1、$2... 参数位置
+var rawPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+// bindArg 追加绑定参数并返回方言对应的占位符
+func (o *OrmModel) bindArg(arg any) string {
+	o.args = append(o.args, arg)
+	return o.dialect().Placeholder(len(o.args))
+}
+
+// bindRaw 将表达式中的 $1、$2... 按出现顺序替换为绑定参数占位符；没有 $n 时按顺序替换 ?，
+// 两者都没有或 ? 的数量与 args 不一致时设置 o.err
+func (o *OrmModel) bindRaw(express string, args []any) string {
+	if len(args) == 0 {
+		return express
+	}
+	if !rawPlaceholder.MatchString(express) {
+		if n := strings.Count(express, "?"); n != len(args) {
+			o.err = fmt.Errorf("raw expression %q has %d placeholders but %d args", express, n, len(args))
+			return express
+		}
+		var b strings.Builder
+		for _, arg := range args {
+			before, after, _ := strings.Cut(express, "?")
+			v, _ := valueToArg(arg)
+			b.WriteString(before)
+			b.WriteString(o.bindArg(v))
+			express = after
+		}
+		b.WriteString(express)
+		return b.String()
+	}
+	return rawPlaceholder.ReplaceAllStringFunc(express, func(s string) string {
+		i, _ := strconv.Atoi(s[1:])
+		if i < 1 || i > len(args) {
+			return s
+		}
+		v, _ := valueToArg(args[i-1])
+		return o.bindArg(v)
+	})
+}
+
+// valueToArg 将字段值转换为绑定参数，值为 nil 时 ok 为 false
+func valueToArg(v any) (arg any, ok bool) {
+	switch vv := v.(type) {
+	case nil:
+		return nil, false
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool, []byte:
+		return vv, true
+	case *string:
+		if vv == nil {
+			return nil, false
+		}
+		return *vv, true
+	case time.Time:
+		return vv, true
+	case *time.Time:
+		if vv == nil {
+			return nil, false
+		}
+		return *vv, true
+	case driver.Valuer:
+		// 由 database/sql 调用 Value
+		if isNilPointer(vv) {
+			return nil, false
+		}
+		return vv, true
+	default:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+			// *int64、*bool 等指针字段，nil 表示 NULL
+			if rv.IsNil() {
+				return nil, false
+			}
+			return valueToArg(rv.Elem().Interface())
+		}
+		jsonStr, err := jsoniter.MarshalToString(v)
+		if err != nil || jsonStr == "null" {
+			return nil, false
+		}
+		return jsonStr, true
+	}
+}
+
+// driverValue 调用 Valuer.Value，nil 指针返回 nil
+func driverValue(vr driver.Valuer) (driver.Value, error) {
+	if isNilPointer(vr) {
+		return nil, nil
+	}
+	return vr.Value()
+}
+
+// isNullValue v 是否表示 NULL：nil、nil 指针或 Value 为 nil 的 driver.Valuer
+func isNullValue(v any) bool {
+	if v == nil || isNilPointer(v) {
+		return true
+	}
+	if vr, ok := v.(driver.Valuer); ok {
+		val, err := vr.Value()
+		return err == nil && val == nil
+	}
+	return false
+}
+
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// isBlankArg 参数是否为 nil 或空字符串，driver.Valuer 按其 Value 判断
+func isBlankArg(arg any) bool {
+	switch v := arg.(type) {
+	case nil:
+		return true
+	case string:
+		return len(v) == 0
+	case time.Time:
+		return v.IsZero()
+	case driver.Valuer:
+		val, err := driverValue(v)
+		return err != nil || isBlankArg(val)
+	}
+	return false
+}
+
+// isZeroArg 参数是否为 nil、空字符串或数值 0
+func isZeroArg(arg any) bool {
+	switch arg.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf(`%v`, arg) == "0"
+	case driver.Valuer:
+		val, err := driverValue(arg.(driver.Valuer))
+		return err != nil || isZeroArg(val)
+	}
+	return isBlankArg(arg)
+}
+
+// ValueTypeToStr 将值格式化为 SQL 字面量，仅用于日志和调试，构造 SQL 请使用绑定参数
 func ValueTypeToStr(v any) string {
 	switch v.(type) {
 	case nil:
 		return ""
 	case string:
-		return fmt.Sprintf(`'%v'`, v)
+		return quoteLiteral(v.(string))
 	case *string:
 		pf := v.(*string)
 		if pf == nil {
 			return ""
 		}
-		return fmt.Sprintf(`'%s'`, *v.(*string))
+		return quoteLiteral(*pf)
 	case int, int16, int32, int64, float32, float64, uint, uint8, uint16, uint32, uint64, bool:
 		return fmt.Sprintf(`%v`, v)
+	case []byte:
+		return quoteLiteral(string(v.([]byte)))
+	case time.Time:
+		return quoteLiteral(v.(time.Time).Format(timeLayout))
+	case *time.Time:
+		pt := v.(*time.Time)
+		if pt == nil {
+			return ""
+		}
+		return quoteLiteral(pt.Format(timeLayout))
+	case driver.Valuer:
+		val, err := driverValue(v.(driver.Valuer))
+		if err != nil {
+			return ""
+		}
+		return ValueTypeToStr(val)
 	default:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return ""
+			}
+			return ValueTypeToStr(rv.Elem().Interface())
+		}
 		jsonStr, err := jsoniter.MarshalToString(v)
 		if err != nil || jsonStr == "null" {
 			return ""
 		}
-		return fmt.Sprintf(`'%s'`, jsonStr)
+		return quoteLiteral(jsonStr)
 	}
 }
+
+// quoteLiteral 单引号包裹字符串并转义其中的单引号
+func quoteLiteral(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
@@ -0,0 +1,90 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestBindArgs(t *testing.T) {
+	SqlxDB = sqlx.NewDb(nil, "postgres")
+
+	sp := SELECT(TestTable{Name: "O'Brien"}).Where(And("name"), IN("type", 1, 2), Raw(`id>$2 AND id<$1`, 10, 1)).
+		BuildSQL()
+	wantSQL := `SELECT  * FROM "test_table" WHERE (name=$1) AND type IN ($2,$3) AND (id>$4 AND id<$5)`
+	if sp.Sql != wantSQL {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+	if want := []any{"O'Brien", 1, 2, 1, 10}; !reflect.DeepEqual(sp.Args, want) {
+		t.Fatalf("args = %v", sp.Args)
+	}
+
+	sp = UPDATE(TestTable{ID: 9, Name: "a'b"}).SetField("type", "x'; DROP TABLE t; --").WherePK().BuildSQL()
+	wantSQL = `UPDATE "test_table" SET type=$1 WHERE (id=$2)`
+	if sp.Sql != wantSQL {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+	if want := []any{"x'; DROP TABLE t; --", 9}; !reflect.DeepEqual(sp.Args, want) {
+		t.Fatalf("args = %v", sp.Args)
+	}
+}
+
+func TestBindArgsQuestion(t *testing.T) {
+	SqlxDB = sqlx.NewDb(nil, "mysql")
+
+	sp := UPDATE(TestTable{ID: 9, Name: "name"}).Where(Like("name"), Raw(`type=$1`, 3)).WherePK().BuildSQL()
+	wantSQL := "UPDATE `test_table` SET name=? WHERE (name LIKE ?) AND (type=?) AND (id=?)"
+	if sp.Sql != wantSQL {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+	if want := []any{"name", "%name%", 3, 9}; !reflect.DeepEqual(sp.Args, want) {
+		t.Fatalf("args = %v", sp.Args)
+	}
+}
+
+func TestNamedQueryCast(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"id"}
+	f.rows = [][]driver.Value{{int64(1)}}
+	var id int
+	query := `SELECT id FROM t WHERE created_at::date = :d AND id = :id AND name::text <> ':x' AND note = :missing`
+	if err := db.NamedQueryWithMap(query, map[string]any{"d": "2024-01-01", "id": 2}, &id); err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT id FROM t WHERE created_at::date = $1 AND id = $2 AND name::text <> ':x' AND note = :missing`
+	if f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	if want := []driver.Value{"2024-01-01", int64(2)}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+	if id != 1 {
+		t.Fatalf("id = %d", id)
+	}
+}
+
+func TestRawQuestionPlaceholder(t *testing.T) {
+	pg := NewDB(sqlx.NewDb(nil, "postgres"))
+	sp := pg.SELECT(TestTable{}).Where(Raw(`id > ? AND type = ?`, 1, 2)).BuildSQL()
+	if want := `SELECT  * FROM "test_table" WHERE (id > $1 AND type = $2)`; sp.Sql != want || sp.Err != nil {
+		t.Fatalf("sql = %s, err = %v", sp.Sql, sp.Err)
+	}
+	if want := []any{1, 2}; !reflect.DeepEqual(sp.Args, want) {
+		t.Fatalf("args = %v", sp.Args)
+	}
+
+	my := NewDB(sqlx.NewDb(nil, "mysql"))
+	sp = my.SELECT(TestTable{}).Where(Raw(`a = ?`, "x")).BuildSQL()
+	if want := "SELECT  * FROM `test_table` WHERE (a = ?)"; sp.Sql != want || !reflect.DeepEqual(sp.Args, []any{"x"}) {
+		t.Fatalf("sql = %s, args = %v", sp.Sql, sp.Args)
+	}
+
+	// 参数没有对应的占位符时返回错误，而不是丢弃参数
+	for _, express := range []string{`a = 1`, `a = ? AND b = ?`} {
+		if sp = pg.SELECT(TestTable{}).Where(Raw(express, 1)).BuildSQL(); sp.Err == nil {
+			t.Fatalf("%s: expected error", express)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package mworm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// CopyIn 使用 COPY FROM STDIN 批量导入，仅支持 postgres
+//
+// rows 为结构体(指针)切片或 channel，channel 关闭后结束；列由 db tag 决定，excludeTags 为排除的 json tag。
+// 在事务中执行，返回写入的行数
+func (d *DB) CopyIn(rows any, excludeTags ...string) (int64, error) {
+	return d.CopyInContext(context.Background(), rows, excludeTags...)
+}
+
+// CopyInContext 使用 ctx 批量导入，ctx 取消时停止读取 channel 并回滚
+func (d *DB) CopyInContext(ctx context.Context, rows any, excludeTags ...string) (int64, error) {
+	if err := d.copySupported(); err != nil {
+		return 0, err
+	}
+	var count int64
+	err := d.BatchFuncContext(ctx, nil, func(tx *Tx) error {
+		var err error
+		count, err = tx.CopyIn(rows, excludeTags...)
+		return err
+	})
+	return count, err
+}
+
+// CopyIn 使用默认实例批量导入
+func CopyIn(rows any, excludeTags ...string) (int64, error) {
+	return defaultDB().CopyIn(rows, excludeTags...)
+}
+
+// CopyInContext 使用默认实例及 ctx 批量导入
+func CopyInContext(ctx context.Context, rows any, excludeTags ...string) (int64, error) {
+	return defaultDB().CopyInContext(ctx, rows, excludeTags...)
+}
+
+// CopyIn 在当前事务中批量导入，出错时 channel 不会被继续读取
+func (tx *Tx) CopyIn(rows any, excludeTags ...string) (int64, error) {
+	if err := tx.db.copySupported(); err != nil {
+		return 0, err
+	}
+	ctx := tx.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	next, entity, err := copyRows(ctx, rows)
+	if err != nil {
+		return 0, err
+	}
+	m := new(OrmModel)
+	m.init()
+	m.structToMap(entity)
+	exclude := make(map[string]emptyKey, len(excludeTags))
+	for _, j := range excludeTags {
+		exclude[j] = emptyKey{}
+	}
+	var tags, columns []string
+	for _, j := range orderedKeys(m.fieldOrder, m.dbFields) {
+		if _, ok := exclude[j]; ok {
+			continue
+		}
+		tags = append(tags, j)
+		columns = append(columns, m.dbFields[j])
+	}
+	if len(columns) == 0 {
+		return 0, ErrEmptySQL
+	}
+
+	stmt, err := tx.sqlxTx.PrepareContext(ctx, copyInSQL(entity.TableName(), columns))
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = stmt.Close() }()
+	var count int64
+	for {
+		row, ok := next()
+		if !ok {
+			break
+		}
+		r := new(OrmModel)
+		r.init()
+		r.structToMap(row)
+		args := make([]any, len(tags))
+		for i, j := range tags {
+			args[i], _ = valueToArg(r.params[j])
+		}
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err = ctx.Err(); err != nil {
+		return count, err
+	}
+	// 无参数 Exec 结束 COPY
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// copySupported COPY FROM STDIN 由 lib/pq 驱动实现，pgx 等同样使用 postgres 方言的驱动不支持
+func (d *DB) copySupported() error {
+	name := d.DriverName()
+	if name == "postgres" {
+		return nil
+	}
+	if d.sqlxDB != nil && d.sqlxDB.DB != nil {
+		if _, ok := d.sqlxDB.Driver().(*pq.Driver); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("CopyIn is not supported by driver %q, it requires github.com/lib/pq", name)
+}
+
+// copyInSQL COPY 语句，表名支持 schema.table
+func copyInSQL(table string, columns []string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return pq.CopyInSchema(schema, name, columns...)
+	}
+	return pq.CopyIn(table, columns...)
+}
+
+// copyRows 返回逐行读取 rows 的函数，rows 为切片或 channel
+func copyRows(ctx context.Context, rows any) (next func() (any, bool), entity ORMInterface, err error) {
+	v := reflect.Indirect(reflect.ValueOf(rows))
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if entity, err = elemEntity(v.Type().Elem()); err != nil {
+			return nil, nil, err
+		}
+		i := 0
+		next = func() (any, bool) {
+			for ; i < v.Len(); i++ {
+				if ctx.Err() != nil {
+					return nil, false
+				}
+				if e := reflect.Indirect(v.Index(i)); e.IsValid() {
+					i++
+					return e.Interface(), true
+				}
+			}
+			return nil, false
+		}
+	case reflect.Chan:
+		if entity, err = elemEntity(v.Type().Elem()); err != nil {
+			return nil, nil, err
+		}
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: v},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		next = func() (any, bool) {
+			for {
+				chosen, e, ok := reflect.Select(cases)
+				if chosen != 0 || !ok {
+					return nil, false
+				}
+				if e = reflect.Indirect(e); e.IsValid() {
+					return e.Interface(), true
+				}
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("CopyIn: rows must be a slice or channel, got %T", rows)
+	}
+	return next, entity, nil
+}
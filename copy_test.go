@@ -0,0 +1,72 @@
+package mworm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestCopyIn(t *testing.T) {
+	fake, f := newFakeDB(t)
+	// 测试驱动以 postgres 名称注册到 sqlx，模拟 lib/pq
+	db := NewDB(sqlx.NewDb(fake.Sqlx().DB, "postgres"))
+	n, err := db.CopyIn([]*TestTable{{Name: "a", Type: 1}, nil, {Name: "b", Images: []string{"x"}}}, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("rows = %d", n)
+	}
+	copySQL := `COPY "test_table" ("name", "type", "created_at", "images") FROM STDIN`
+	want := []string{"BEGIN", copySQL, copySQL, copySQL, "COMMIT"}
+	if got := f.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements = %q", got)
+	}
+	if got := f.args[2][3]; got != `["x"]` {
+		t.Fatalf("images = %v", got)
+	}
+
+	ch := make(chan TestTable)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 3; i++ {
+			ch <- TestTable{ID: i + 1}
+		}
+	}()
+	if n, err = db.CopyIn(ch); err != nil || n != 3 {
+		t.Fatalf("channel rows = %d, err = %v", n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = db.CopyInContext(ctx, make(chan TestTable)); err == nil {
+		t.Fatal("canceled ctx should return error")
+	}
+
+	if _, err = NewDB(sqlx.NewDb(nil, "mysql")).CopyIn([]TestTable{}); err == nil {
+		t.Fatal("mysql CopyIn should return error")
+	}
+	// pgx 等驱动同样使用 postgres 方言，但不支持 lib/pq 的 COPY
+	stmts := len(f.statements())
+	if _, err = NewDB(sqlx.NewDb(fake.Sqlx().DB, "pgx")).CopyIn([]TestTable{{ID: 1}}); err == nil {
+		t.Fatal("pgx CopyIn should return error")
+	}
+	if len(f.statements()) != stmts {
+		t.Fatal("unsupported CopyIn should not execute statements")
+	}
+	if err = fake.copySupported(); err == nil {
+		t.Fatal("fake driver CopyIn should return error")
+	}
+	// 以其他名称注册的 lib/pq 驱动
+	pqDB, err := sql.Open("postgres", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pqDB.Close()
+	if err = NewDB(sqlx.NewDb(pqDB, "cloudsqlpostgres")).copySupported(); err != nil {
+		t.Fatalf("lib/pq driver: %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+package mworm
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// DB 数据库句柄，每个 DB 拥有独立的连接，可同时操作多个数据库
+type DB struct {
+	sqlxDB      *sqlx.DB
+	dialect     Dialect
+	middlewares []Middleware
+}
+
+// stdDB BindDB 绑定的默认实例，供包级函数使用
+var stdDB *DB
+
+// NewDB 使用 *sqlx.DB 创建数据库句柄，方言由驱动名称决定
+func NewDB(db *sqlx.DB) *DB {
+	d := &DB{sqlxDB: db}
+	d.dialect = DialectFor(d.DriverName())
+	return d
+}
+
+// Open 打开数据库连接并 Ping
+func Open(driverName, dataSourceName string) (*DB, error) {
+	db, err := sqlx.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return NewDB(db), nil
+}
+
+// defaultDB 返回包级函数使用的实例，始终与 SqlxDB 保持一致
+func defaultDB() *DB {
+	if d := stdDB; d != nil && d.sqlxDB == SqlxDB {
+		return d
+	}
+	return NewDB(SqlxDB)
+}
+
+// Sqlx 返回底层 *sqlx.DB
+func (d *DB) Sqlx() *sqlx.DB {
+	return d.sqlxDB
+}
+
+// DriverName 返回驱动名称，未连接时为空
+func (d *DB) DriverName() string {
+	if d == nil || d.sqlxDB == nil {
+		return ""
+	}
+	return d.sqlxDB.DriverName()
+}
+
+// Dialect 返回数据库方言
+func (d *DB) Dialect() Dialect {
+	if d.dialect == nil {
+		return PostgresDialect{}
+	}
+	return d.dialect
+}
+
+// SetDialect 指定数据库方言，用于未注册的驱动
+func (d *DB) SetDialect(dialect Dialect) *DB {
+	d.dialect = dialect
+	return d
+}
+
+// Table 指定表名
+func (d *DB) Table(name string) *OrmModel {
+	o := &OrmModel{db: d, table: name}
+	o.init()
+	o.tableName = d.Dialect().Quote(name)
+	return o
+}
+
+// SELECT 查询
+func (d *DB) SELECT(i ORMInterface, distinct ...bool) *OrmModel {
+	return d.Table(i.TableName()).setMethod(methodSelect, i, distinct...)
+}
+
+// INSERT 插入
+func (d *DB) INSERT(i ORMInterface) *OrmModel {
+	return d.Table(i.TableName()).setMethod(methodInsert, i)
+}
+
+// UPDATE 更新
+func (d *DB) UPDATE(i ORMInterface) *OrmModel {
+	return d.Table(i.TableName()).setMethod(methodUpdate, i)
+}
+
+// DELETE 删除
+func (d *DB) DELETE(i ORMInterface) *OrmModel {
+	return d.Table(i.TableName()).setMethod(methodDelete, i)
+}
+
+// RawSQL 原生 SQL 查询，args 按驱动占位符顺序绑定
+func (d *DB) RawSQL(sql string, args ...any) *OrmModel {
+	o := O()
+	o.db = d
+	if len(sql) == 0 {
+		o.err = errors.New("invalid sql")
+	}
+	o.rawSQL = true
+	o.sql = sql
+	o.args = args
+	return o
+}
+
+// RawNamedSQL 带命名参数的原生 SQL
+func (d *DB) RawNamedSQL(sql string, params any) *OrmModel {
+	o := d.RawSQL(sql)
+	o.setMethod(o.method, params)
+	if params != nil {
+		o.namedExec = true
+	}
+	return o
+}
+
+// ExecRawSQL 执行原生 SQL
+func (d *DB) ExecRawSQL(sql string, args ...any) error {
+	return d.ExecRawSQLContext(context.Background(), sql, args...)
+}
+
+// ExecRawSQLContext 使用 ctx 执行原生 SQL
+func (d *DB) ExecRawSQLContext(ctx context.Context, sql string, args ...any) error {
+	if d.sqlxDB == nil {
+		return ErrNilDB
+	}
+	_, err := d.ext().ExecContext(ctx, sql, args...)
+	return err
+}
+
+// Exec 执行 SQL 语句，影响行数为 0 时返回错误
+func (d *DB) Exec(sqlStr string, args ...any) error {
+	return d.ExecContext(context.Background(), sqlStr, args...)
+}
+
+// ExecContext 使用 ctx 执行 SQL 语句，影响行数为 0 时返回错误
+func (d *DB) ExecContext(ctx context.Context, sqlStr string, args ...any) error {
+	if d.sqlxDB == nil {
+		return ErrNilDB
+	}
+	return checkAffected(execContext(ctx, d.ext(), sqlStr, args...))
+}
+
+// NamedExec 执行带命名参数的 SQL 语句
+func (d *DB) NamedExec(sqlStr string, params map[string]interface{}) error {
+	return d.NamedExecContext(context.Background(), sqlStr, params)
+}
+
+// NamedExecContext 使用 ctx 执行带命名参数的 SQL 语句
+func (d *DB) NamedExecContext(ctx context.Context, sqlStr string, params map[string]interface{}) error {
+	if d.sqlxDB == nil {
+		return ErrNilDB
+	}
+	return checkAffected(namedExecContext(ctx, d.ext(), sqlStr, params))
+}
+
+// Query 执行 SQL 查询并将第一行映射到 dest，args 按驱动占位符顺序绑定
+func (d *DB) Query(query string, dest any, args ...any) error {
+	return d.QueryContext(context.Background(), query, dest, args...)
+}
+
+// QueryContext 使用 ctx 执行 SQL 查询并将第一行映射到 dest
+func (d *DB) QueryContext(ctx context.Context, query string, dest any, args ...any) error {
+	if d.sqlxDB == nil {
+		return ErrNilDB
+	}
+	return queryContext(ctx, d.ext(), query, dest, args...)
+}
+
+// NamedQuery 执行带命名参数的 SQL 查询并映射结果，:name 按 params 绑定为参数，::type 类型转换与 params 中不存在的名称原样保留
+func (d *DB) NamedQuery(query string, params any, dest any) error {
+	return d.NamedQueryContext(context.Background(), query, params, dest)
+}
+
+// NamedQueryContext 使用 ctx 执行带命名参数的 SQL 查询并映射结果
+func (d *DB) NamedQueryContext(ctx context.Context, query string, params any, dest any) error {
+	fieldMap, _ := StructToMap(params)
+	return d.NamedQueryWithMapContext(ctx, query, fieldMap, dest)
+}
+
+// NamedQueryWithMap 执行带命名参数的 SQL 查询并映射结果
+func (d *DB) NamedQueryWithMap(query string, fieldMap map[string]any, dest any) error {
+	return d.NamedQueryWithMapContext(context.Background(), query, fieldMap, dest)
+}
+
+// NamedQueryWithMapContext 使用 ctx 执行带命名参数的 SQL 查询并映射结果
+func (d *DB) NamedQueryWithMapContext(ctx context.Context, query string, fieldMap map[string]any, dest any) error {
+	if d.sqlxDB == nil {
+		return ErrNilDB
+	}
+	return namedQueryContext(ctx, d.ext(), d.Dialect(), query, fieldMap, dest)
+}
+
+// Begin 开启事务
+func (d *DB) Begin() (*Tx, error) {
+	return d.BeginTx(context.Background(), nil)
+}
+
+// BeginTx 使用 ctx 和事务选项开启事务，事务内的 SQL 默认使用该 ctx
+func (d *DB) BeginTx(ctx context.Context, opts *dbsql.TxOptions) (*Tx, error) {
+	if d.sqlxDB == nil {
+		return nil, ErrNilDB
+	}
+	tx, err := d.sqlxDB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{sqlxTx: tx, db: d, ctx: ctx}, nil
+}
+
+// BatchArray 批量插入/更新
+func (d *DB) BatchArray(ormArray []*OrmModel) error {
+	return d.BatchArrayContext(context.Background(), nil, ormArray)
+}
+
+// Batch 批量插入/更新
+func (d *DB) Batch(ormArray ...*OrmModel) error {
+	return d.BatchArray(ormArray)
+}
+
+// BatchArrayContext 使用 ctx 和事务选项批量插入/更新
+func (d *DB) BatchArrayContext(ctx context.Context, opts *dbsql.TxOptions, ormArray []*OrmModel) error {
+	return d.BatchFuncContext(ctx, opts, func(tx *Tx) error {
+		for _, i := range ormArray {
+			o := i
+			if o == nil {
+				continue
+			}
+			if _, err := o.execInTx(tx, ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BatchFunc 在事务中执行 f，f 返回错误时回滚，否则提交
+func (d *DB) BatchFunc(f func(tx *Tx) error) error {
+	return d.BatchFuncContext(context.Background(), nil, f)
+}
+
+// BatchFuncContext 使用 ctx 和事务选项在事务中执行 f，f 返回错误时回滚，否则提交
+func (d *DB) BatchFuncContext(ctx context.Context, opts *dbsql.TxOptions, f func(tx *Tx) error) error {
+	if f == nil {
+		return nil
+	}
+	tx, err := d.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err = f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execContext 执行 SQL 并返回影响行数
+func execContext(ctx context.Context, e sqlx.ExtContext, sqlStr string, args ...any) (count int64, err error) {
+	defer recoverExec(&err)
+	result, err := e.ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// namedExecContext 执行带命名参数的 SQL 并返回影响行数
+func namedExecContext(ctx context.Context, e sqlx.ExtContext, sqlStr string, params any) (count int64, err error) {
+	defer recoverExec(&err)
+	result, err := sqlx.NamedExecContext(ctx, e, sqlStr, params)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func recoverExec(err *error) {
+	if e := recover(); e != nil {
+		if pqErr, ok := e.(*pq.Error); ok {
+			*err = errors.New(pqErr.Message)
+		} else {
+			*err = fmt.Errorf("%v", e)
+		}
+	}
+}
+
+// checkAffected 影响行数为 0 时返回错误
+func checkAffected(count int64, err error) error {
+	if count == 0 && err == nil {
+		err = errors.New(`影响行数为0`)
+	}
+	return err
+}
+
+func queryContext(ctx context.Context, e sqlx.ExtContext, query string, dest any, args ...any) error {
+	rows, err := e.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return rowsMapScan(ctx, rows, dest)
+}
+
+func namedQueryContext(ctx context.Context, e sqlx.ExtContext, d Dialect, query string, fieldMap map[string]any, dest any) error {
+	query, args := bindNamed(query, fieldMap, d)
+	return queryContext(ctx, e, query, dest, args...)
+}
+
+// bindNamed 将 query 中的 :name 按 fieldMap 替换为方言对应的绑定参数占位符，
+// postgres 的 ::type 类型转换及 fieldMap 中不存在的名称原样保留
+func bindNamed(query string, fieldMap map[string]any, d Dialect) (string, []any) {
+	var b strings.Builder
+	var args []any
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c != ':' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == ':' {
+			b.WriteString("::")
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(query) && isNameByte(query[end]) {
+			end++
+		}
+		v, ok := fieldMap[query[i+1:end]]
+		if end == i+1 || !ok {
+			b.WriteByte(c)
+			continue
+		}
+		arg, _ := valueToArg(v)
+		args = append(args, arg)
+		b.WriteString(d.Placeholder(len(args)))
+		i = end - 1
+	}
+	return b.String(), args
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c == '.' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
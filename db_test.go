@@ -0,0 +1,32 @@
+package mworm
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMultipleDB(t *testing.T) {
+	pg := NewDB(sqlx.NewDb(nil, "postgres"))
+	my := NewDB(sqlx.NewDb(nil, "mysql"))
+
+	sp := pg.SELECT(TestTable{ID: 1}).WherePK().BuildSQL()
+	if want := `SELECT  * FROM "test_table" WHERE (id=$1)`; sp.Sql != want {
+		t.Fatalf("postgres sql = %s", sp.Sql)
+	}
+	sp = my.SELECT(TestUser{ID: 1}).WherePK().BuildSQL()
+	if want := "SELECT  * FROM `c_user` WHERE (id=?)"; sp.Sql != want {
+		t.Fatalf("mysql sql = %s", sp.Sql)
+	}
+}
+
+func TestNilDB(t *testing.T) {
+	db := NewDB(nil)
+	var list []TestTable
+	if err := db.SELECT(TestTable{}).Many(&list); err != ErrNilDB {
+		t.Fatalf("Many err = %v", err)
+	}
+	if err := db.Exec(`DELETE FROM test_table`); err != ErrNilDB {
+		t.Fatalf("Exec err = %v", err)
+	}
+}
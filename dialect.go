@@ -0,0 +1,205 @@
+package mworm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Dialect 数据库方言，屏蔽不同数据库之间的 SQL 差异
+type Dialect interface {
+	Name() string                            // 方言名称 postgres / mysql / sqlite
+	Quote(ident string) string               // 引用表名等标识符，schema.table 分段引用
+	Placeholder(n int) string                // 第 n 个绑定参数的占位符，n 从 1 开始
+	LimitOffset(limit, offset int64) string  // LIMIT / OFFSET 子句，包含前导空格
+	SupportsReturning() bool                 // 是否支持 RETURNING
+	JSONAgg(expr string) string              // 将多行聚合为 JSON 数组
+	JSONObjectAgg(keyValue string) string    // 将 key,value 聚合为 JSON 对象
+	JSONBuildObject(pairs string) string     // 由 'key',value,... 构造 JSON 对象
+	Upsert(conflict, update []string) string // 冲突处理子句，update 为空时忽略冲突
+	MaxPlaceholders() int                    // 单条语句允许的最大绑定参数数量
+}
+
+var (
+	dialectMu sync.RWMutex
+	dialects  = map[string]Dialect{
+		"postgres":         PostgresDialect{},
+		"pgx":              PostgresDialect{},
+		"pq-timeouts":      PostgresDialect{},
+		"cloudsqlpostgres": PostgresDialect{},
+		"nrpostgres":       PostgresDialect{},
+		"cockroach":        PostgresDialect{},
+		"mysql":            MySQLDialect{},
+		"nrmysql":          MySQLDialect{},
+		"sqlite3":          SQLiteDialect{},
+		"sqlite":           SQLiteDialect{},
+		"nrsqlite3":        SQLiteDialect{},
+	}
+)
+
+// RegisterDialect 为驱动名称注册方言
+func RegisterDialect(driverName string, d Dialect) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	dialects[driverName] = d
+}
+
+// DialectFor 根据驱动名称返回方言，未注册的驱动使用 PostgresDialect
+func DialectFor(driverName string) Dialect {
+	dialectMu.RLock()
+	defer dialectMu.RUnlock()
+	if d, ok := dialects[driverName]; ok {
+		return d
+	}
+	return PostgresDialect{}
+}
+
+// quoteIdent 使用 q 引用标识符，schema.table 分段引用
+func quoteIdent(ident string, q string) string {
+	parts := strings.Split(ident, ".")
+	for i, p := range parts {
+		parts[i] = q + strings.ReplaceAll(p, q, q+q) + q
+	}
+	return strings.Join(parts, ".")
+}
+
+// PostgresDialect PostgreSQL 方言
+type PostgresDialect struct{}
+
+func (PostgresDialect) Name() string { return "postgres" }
+
+func (PostgresDialect) Quote(ident string) string { return quoteIdent(ident, `"`) }
+
+func (PostgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (PostgresDialect) LimitOffset(limit, offset int64) string {
+	var s string
+	if limit > 0 {
+		s += fmt.Sprintf(` LIMIT %d`, limit)
+	}
+	if offset > 0 {
+		s += fmt.Sprintf(` OFFSET %d`, offset)
+	}
+	return s
+}
+
+func (PostgresDialect) SupportsReturning() bool { return true }
+
+func (PostgresDialect) JSONAgg(expr string) string { return `jsonb_agg(` + expr + `)` }
+
+func (PostgresDialect) JSONObjectAgg(keyValue string) string {
+	return `jsonb_object_agg(` + keyValue + `)`
+}
+
+func (PostgresDialect) JSONBuildObject(pairs string) string {
+	return `jsonb_build_object(` + pairs + `)`
+}
+
+func (PostgresDialect) Upsert(conflict, update []string) string {
+	return onConflict(conflict, update)
+}
+
+func (PostgresDialect) MaxPlaceholders() int { return 65535 }
+
+// onConflict PostgreSQL / SQLite 的 ON CONFLICT 子句
+func onConflict(conflict, update []string) string {
+	var target string
+	if len(conflict) > 0 {
+		target = ` (` + strings.Join(conflict, `, `) + `)`
+	}
+	if len(update) == 0 {
+		return ` ON CONFLICT` + target + ` DO NOTHING`
+	}
+	sets := make([]string, 0, len(update))
+	for _, c := range update {
+		sets = append(sets, c+`=EXCLUDED.`+c)
+	}
+	return ` ON CONFLICT` + target + ` DO UPDATE SET ` + strings.Join(sets, `, `)
+}
+
+// MySQLDialect MySQL 方言
+type MySQLDialect struct{}
+
+func (MySQLDialect) Name() string { return "mysql" }
+
+func (MySQLDialect) Quote(ident string) string { return quoteIdent(ident, "`") }
+
+func (MySQLDialect) Placeholder(int) string { return "?" }
+
+func (MySQLDialect) LimitOffset(limit, offset int64) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return fmt.Sprintf(` LIMIT %d OFFSET %d`, limit, offset)
+	case limit > 0:
+		return fmt.Sprintf(` LIMIT %d`, limit)
+	case offset > 0:
+		// MySQL 的 OFFSET 必须跟在 LIMIT 之后
+		return fmt.Sprintf(` LIMIT 18446744073709551615 OFFSET %d`, offset)
+	}
+	return ""
+}
+
+func (MySQLDialect) SupportsReturning() bool { return false }
+
+func (MySQLDialect) JSONAgg(expr string) string { return `JSON_ARRAYAGG(` + expr + `)` }
+
+func (MySQLDialect) JSONObjectAgg(keyValue string) string { return `JSON_OBJECTAGG(` + keyValue + `)` }
+
+func (MySQLDialect) JSONBuildObject(pairs string) string { return `JSON_OBJECT(` + pairs + `)` }
+
+func (MySQLDialect) Upsert(conflict, update []string) string {
+	if len(update) == 0 {
+		if len(conflict) == 0 {
+			return ""
+		}
+		// 没有 DO NOTHING，将冲突列赋值为自身
+		return ` ON DUPLICATE KEY UPDATE ` + conflict[0] + `=` + conflict[0]
+	}
+	sets := make([]string, 0, len(update))
+	for _, c := range update {
+		sets = append(sets, c+`=VALUES(`+c+`)`)
+	}
+	return ` ON DUPLICATE KEY UPDATE ` + strings.Join(sets, `, `)
+}
+
+func (MySQLDialect) MaxPlaceholders() int { return 65535 }
+
+// SQLiteDialect SQLite 方言
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) Name() string { return "sqlite" }
+
+func (SQLiteDialect) Quote(ident string) string { return quoteIdent(ident, `"`) }
+
+func (SQLiteDialect) Placeholder(int) string { return "?" }
+
+func (SQLiteDialect) LimitOffset(limit, offset int64) string {
+	switch {
+	case limit > 0 && offset > 0:
+		return fmt.Sprintf(` LIMIT %d OFFSET %d`, limit, offset)
+	case limit > 0:
+		return fmt.Sprintf(` LIMIT %d`, limit)
+	case offset > 0:
+		// SQLite 的 OFFSET 必须跟在 LIMIT 之后，-1 表示不限制
+		return fmt.Sprintf(` LIMIT -1 OFFSET %d`, offset)
+	}
+	return ""
+}
+
+func (SQLiteDialect) SupportsReturning() bool { return true }
+
+func (SQLiteDialect) JSONAgg(expr string) string { return `json_group_array(` + expr + `)` }
+
+func (SQLiteDialect) JSONObjectAgg(keyValue string) string {
+	return `json_group_object(` + keyValue + `)`
+}
+
+func (SQLiteDialect) JSONBuildObject(pairs string) string { return `json_object(` + pairs + `)` }
+
+func (SQLiteDialect) Upsert(conflict, update []string) string {
+	return onConflict(conflict, update)
+}
+
+// MaxPlaceholders SQLite 3.32 之前为 999
+func (SQLiteDialect) MaxPlaceholders() int { return 32766 }
@@ -0,0 +1,46 @@
+package mworm
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestDialect(t *testing.T) {
+	cases := []struct {
+		driver string
+		want   string
+	}{
+		{"postgres", `SELECT  * FROM "public"."test_table" WHERE (id=$1) LIMIT 10 OFFSET 20`},
+		{"mysql", "SELECT  * FROM `public`.`test_table` WHERE (id=?) LIMIT 10 OFFSET 20"},
+		{"sqlite3", `SELECT  * FROM "public"."test_table" WHERE (id=?) LIMIT 10 OFFSET 20`},
+	}
+	for _, c := range cases {
+		db := NewDB(sqlx.NewDb(nil, c.driver))
+		sp := db.Table("public.test_table").Select(TestTable{ID: 1}).WherePK().Limit(10).Offset(20).BuildSQL()
+		if sp.Sql != c.want {
+			t.Errorf("%s sql = %s", c.driver, sp.Sql)
+		}
+	}
+
+	if s := (MySQLDialect{}).LimitOffset(0, 5); s != ` LIMIT 18446744073709551615 OFFSET 5` {
+		t.Errorf("mysql offset = %s", s)
+	}
+	if s := (SQLiteDialect{}).JSONAgg((SQLiteDialect{}).JSONBuildObject(`'id',id`)); s != `json_group_array(json_object('id',id))` {
+		t.Errorf("sqlite json = %s", s)
+	}
+	if s := (MySQLDialect{}).JSONObjectAgg(`id,name`); s != `JSON_OBJECTAGG(id,name)` {
+		t.Errorf("mysql json = %s", s)
+	}
+
+	my := NewDB(sqlx.NewDb(nil, "mysql"))
+	var dest TestTable
+	if err := my.INSERT(TestTable{Name: "a"}).RETURNING(&dest, nil); err == nil {
+		t.Fatal("mysql RETURNING should return error")
+	}
+
+	RegisterDialect("mwormdialect", MySQLDialect{})
+	if d := DialectFor("mwormdialect"); d.Name() != "mysql" {
+		t.Fatalf("registered dialect = %s", d.Name())
+	}
+}
@@ -0,0 +1,180 @@
+package mworm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// fakeDriverName 测试用驱动，记录执行的语句并返回预设结果
+const fakeDriverName = "mwormtest"
+
+var fakeDBs sync.Map // dsn -> *fakeDB
+
+func init() {
+	sql.Register(fakeDriverName, fakeDriver{})
+	sqlx.BindDriver(fakeDriverName, sqlx.DOLLAR)
+}
+
+type fakeDB struct {
+	mu       sync.Mutex
+	stmts    []string         // 执行过的语句，包括 BEGIN/COMMIT/ROLLBACK
+	args     [][]driver.Value // 与 stmts 对应的参数
+	affected int64            // Exec 返回的影响行数
+	columns  []string         // 查询返回的列
+	rows     [][]driver.Value // 查询返回的行
+	failOn   string           // 语句包含该子串时返回错误
+	open     int              // 未关闭的结果集数量
+}
+
+// newFakeDB 创建使用测试驱动的 DB
+func newFakeDB(t testing.TB) (*DB, *fakeDB) {
+	f := &fakeDB{affected: 1}
+	fakeDBs.Store(t.Name(), f)
+	t.Cleanup(func() { fakeDBs.Delete(t.Name()) })
+	return NewDB(sqlx.MustOpen(fakeDriverName, t.Name())), f
+}
+
+func (f *fakeDB) record(query string, args []driver.Value) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stmts = append(f.stmts, query)
+	f.args = append(f.args, args)
+	if f.failOn != "" && strings.Contains(query, f.failOn) {
+		return errors.New("fake error: " + query)
+	}
+	return nil
+}
+
+// openRows 返回未关闭的结果集数量
+func (f *fakeDB) openRows() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.open
+}
+
+// statements 返回执行过的语句
+func (f *fakeDB) statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.stmts...)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	f, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, errors.New("unknown fake db: " + name)
+	}
+	return &fakeConn{db: f.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if err := c.db.record("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return fakeTx{c}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.db.record(query, namedValues(args)); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(c.db.affected), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.db.record(query, namedValues(args)); err != nil {
+		return nil, err
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.open++
+	return &fakeRows{db: c.db, columns: c.db.columns, rows: c.db.rows}, nil
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, 0, len(args))
+	for _, a := range args {
+		values = append(values, a.Value)
+	}
+	return values
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx fakeTx) Commit() error   { return tx.conn.db.record("COMMIT", nil) }
+func (tx fakeTx) Rollback() error { return tx.conn.db.record("ROLLBACK", nil) }
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.conn.db.record(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(s.conn.db.affected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.conn.db.record(s.query, args); err != nil {
+		return nil, err
+	}
+	s.conn.db.mu.Lock()
+	defer s.conn.db.mu.Unlock()
+	s.conn.db.open++
+	return &fakeRows{db: s.conn.db, columns: s.conn.db.columns, rows: s.conn.db.rows}, nil
+}
+
+type fakeRows struct {
+	db      *fakeDB
+	columns []string
+	rows    [][]driver.Value
+	i       int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.open--
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
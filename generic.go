@@ -0,0 +1,55 @@
+package mworm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// newEntity 返回用于构造查询的 T，T 为指针时分配其指向的结构体，使指针接收者实现 ORMInterface 的类型可用
+func newEntity[T ORMInterface]() T {
+	var entity T
+	if t := reflect.TypeOf((*T)(nil)).Elem(); t.Kind() == reflect.Ptr {
+		entity = reflect.New(t.Elem()).Interface().(T)
+	}
+	return entity
+}
+
+// Find 查询 T 对应表中满足条件的全部记录
+func Find[T ORMInterface](cgs ...ConditionGroup) ([]T, error) {
+	return Fetch[T](SELECT(newEntity[T]()).Where(cgs...))
+}
+
+// First 查询 T 对应表中满足条件的第一条记录，没有记录时返回 ErrNotFound
+func First[T ORMInterface](cgs ...ConditionGroup) (T, error) {
+	return FetchOne[T](SELECT(newEntity[T]()).Where(cgs...))
+}
+
+// Get 按主键查询记录，主键由 db tag 的 pk 标记，没有记录时返回 ErrNotFound
+func Get[T ORMInterface](pk any) (T, error) {
+	var zero T
+	o := SELECT(newEntity[T]())
+	if len(o.pk) == 0 {
+		return zero, fmt.Errorf("%T has no pk field", zero)
+	}
+	if arg, _ := valueToArg(pk); isBlankArg(arg) {
+		return zero, ErrNotFound
+	}
+	return FetchOne[T](o.Where(Eq(o.pk, pk)))
+}
+
+// Fetch 执行 o 并将结果映射为 []T，可用于 DB、Tx 及 JOIN 等构造的查询
+func Fetch[T any](o *OrmModel) ([]T, error) {
+	list := make([]T, 0)
+	err := o.Many(&list)
+	return list, err
+}
+
+// FetchOne 执行 o 并将第一条记录映射为 T，没有记录时返回 ErrNotFound
+func FetchOne[T any](o *OrmModel) (T, error) {
+	var dest T
+	found, err := o.one(&dest)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return dest, err
+}
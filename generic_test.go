@@ -0,0 +1,74 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestGeneric(t *testing.T) {
+	db, f := newFakeDB(t)
+	old := SqlxDB
+	SqlxDB = db.Sqlx()
+	t.Cleanup(func() { SqlxDB = old })
+
+	f.columns = []string{"id", "name"}
+	f.rows = [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}
+	list, err := Find[TestTable](Eq("type", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []TestTable{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}; !reflect.DeepEqual(list, want) {
+		t.Fatalf("list = %+v", list)
+	}
+
+	row, err := Get[TestTable](2)
+	if err != nil || row.ID != 1 {
+		t.Fatalf("row = %+v, err = %v", row, err)
+	}
+	if want := `SELECT  * FROM "test_table" WHERE id=$1 LIMIT 1`; f.stmts[len(f.stmts)-1] != want {
+		t.Fatalf("sql = %s", f.stmts[len(f.stmts)-1])
+	}
+
+	f.rows = nil
+	if _, err = First[TestTable](And("name")); err != ErrNotFound {
+		t.Fatalf("First err = %v", err)
+	}
+	if list, err = Fetch[TestTable](db.SELECT(TestTable{})); err != nil || len(list) != 0 {
+		t.Fatalf("Fetch = %+v, err = %v", list, err)
+	}
+}
+
+type TestPtrEntity struct {
+	ID   int    `json:"id" db:"id,pk"`
+	Name string `json:"name" db:"name"`
+}
+
+func (*TestPtrEntity) TableName() string { return "test_ptr_entity" }
+
+func TestGenericPointer(t *testing.T) {
+	db, f := newFakeDB(t)
+	old := SqlxDB
+	SqlxDB = db.Sqlx()
+	t.Cleanup(func() { SqlxDB = old })
+
+	f.columns = []string{"id", "name"}
+	f.rows = [][]driver.Value{{int64(1), "a"}}
+	list, err := Find[*TestPtrEntity]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*TestPtrEntity{{ID: 1, Name: "a"}}; !reflect.DeepEqual(list, want) {
+		t.Fatalf("list = %+v", list)
+	}
+	row, err := Get[*TestPtrEntity](1)
+	if err != nil || row == nil || row.Name != "a" {
+		t.Fatalf("row = %+v, err = %v", row, err)
+	}
+	if want := `SELECT  * FROM "test_ptr_entity" WHERE id=$1 LIMIT 1`; f.stmts[len(f.stmts)-1] != want {
+		t.Fatalf("sql = %s", f.stmts[len(f.stmts)-1])
+	}
+	if row, err = First[*TestPtrEntity](Eq("name", "a")); err != nil || row.ID != 1 {
+		t.Fatalf("row = %+v, err = %v", row, err)
+	}
+}
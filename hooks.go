@@ -0,0 +1,124 @@
+package mworm
+
+import (
+	"context"
+	"reflect"
+)
+
+// BeforeInsertInterface INSERT 前调用，返回错误时不执行，可在其中修改字段
+type BeforeInsertInterface interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInsertInterface INSERT 成功后调用，返回的错误作为执行结果
+type AfterInsertInterface interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// BeforeUpdateInterface UPDATE 前调用，返回错误时不执行，可在其中修改字段
+type BeforeUpdateInterface interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// AfterUpdateInterface UPDATE 成功后调用，返回的错误作为执行结果
+type AfterUpdateInterface interface {
+	AfterUpdate(ctx context.Context) error
+}
+
+// BeforeDeleteInterface DELETE 前调用，返回错误时不执行
+type BeforeDeleteInterface interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// AfterFindInterface One/Many/Iter 每映射一行后调用，返回错误时停止读取
+type AfterFindInterface interface {
+	AfterFind(ctx context.Context) error
+}
+
+var afterFindType = reflect.TypeOf((*AfterFindInterface)(nil)).Elem()
+
+// hookTarget 返回用于调用钩子的实体指针，值类型实体复制一份，使指针接收者的钩子可以修改字段
+func hookTarget(entity any) any {
+	v := reflect.ValueOf(entity)
+	if v.Kind() == reflect.Ptr {
+		return entity
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface()
+}
+
+// beforeHook 执行前调用实体的 BeforeInsert/BeforeUpdate/BeforeDelete，钩子修改的字段会重新映射
+func (o *OrmModel) beforeHook() error {
+	ctx := o.context()
+	if o.bulkRows != nil {
+		for i, row := range o.bulkRows {
+			target := hookTarget(row)
+			if h, ok := target.(BeforeInsertInterface); ok {
+				if err := h.BeforeInsert(ctx); err != nil {
+					return err
+				}
+				o.bulkRows[i] = target
+			}
+		}
+		return nil
+	}
+	if o.entity == nil || o.rawSQL {
+		return nil
+	}
+	target := hookTarget(o.entity)
+	var hook func(context.Context) error
+	switch o.method {
+	case methodInsert:
+		if h, ok := target.(BeforeInsertInterface); ok {
+			hook = h.BeforeInsert
+		}
+	case methodUpdate:
+		if h, ok := target.(BeforeUpdateInterface); ok {
+			hook = h.BeforeUpdate
+		}
+	case methodDelete:
+		if h, ok := target.(BeforeDeleteInterface); ok {
+			hook = h.BeforeDelete
+		}
+	}
+	if hook == nil {
+		return nil
+	}
+	if err := hook(ctx); err != nil {
+		return err
+	}
+	o.entity = target
+	o.structToMap(target)
+	return nil
+}
+
+// afterHook 执行成功后调用实体的 AfterInsert/AfterUpdate
+func (o *OrmModel) afterHook() error {
+	ctx := o.context()
+	if o.bulkRows != nil {
+		for _, row := range o.bulkRows {
+			if h, ok := hookTarget(row).(AfterInsertInterface); ok {
+				if err := h.AfterInsert(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if o.entity == nil || o.rawSQL {
+		return nil
+	}
+	target := hookTarget(o.entity)
+	switch o.method {
+	case methodInsert:
+		if h, ok := target.(AfterInsertInterface); ok {
+			return h.AfterInsert(ctx)
+		}
+	case methodUpdate:
+		if h, ok := target.(AfterUpdateInterface); ok {
+			return h.AfterUpdate(ctx)
+		}
+	}
+	return nil
+}
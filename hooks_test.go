@@ -0,0 +1,119 @@
+package mworm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var errHookAbort = errors.New("abort")
+
+type TestHooked struct {
+	ID    int    `json:"id" db:"id,pk"`
+	Name  string `json:"name" db:"name"`
+	calls *[]string
+}
+
+func (TestHooked) TableName() string { return "test_hooked" }
+
+func (h *TestHooked) record(name string) error {
+	if h.calls != nil {
+		*h.calls = append(*h.calls, name)
+	}
+	if h.Name == "abort" {
+		return errHookAbort
+	}
+	return nil
+}
+
+func (h *TestHooked) BeforeInsert(context.Context) error {
+	if h.Name == "" {
+		h.Name = "default"
+	}
+	return h.record("BeforeInsert")
+}
+
+func (h *TestHooked) AfterInsert(context.Context) error  { return h.record("AfterInsert") }
+func (h *TestHooked) BeforeUpdate(context.Context) error { return h.record("BeforeUpdate") }
+func (h *TestHooked) AfterUpdate(context.Context) error  { return h.record("AfterUpdate") }
+func (h *TestHooked) BeforeDelete(context.Context) error { return h.record("BeforeDelete") }
+
+func (h *TestHooked) AfterFind(context.Context) error {
+	h.Name = "found:" + h.Name
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	db, f := newFakeDB(t)
+	var calls []string
+	if err := db.INSERT(TestHooked{ID: 1, calls: &calls}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []driver.Value{int64(1), "default"}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+	if err := db.UPDATE(&TestHooked{ID: 1, Name: "b", calls: &calls}).WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DELETE(TestHooked{ID: 1, calls: &calls}).WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.INSERTMany([]TestHooked{{ID: 2, calls: &calls}, {ID: 3, calls: &calls}}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"BeforeInsert", "AfterInsert", "BeforeUpdate", "AfterUpdate", "BeforeDelete",
+		"BeforeInsert", "BeforeInsert", "AfterInsert", "AfterInsert"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v", calls)
+	}
+
+	n := len(f.stmts)
+	err := db.BatchFunc(func(tx *Tx) error {
+		return tx.BatchArray([]*OrmModel{tx.INSERT(TestHooked{ID: 4, Name: "abort"})})
+	})
+	if !errors.Is(err, errHookAbort) {
+		t.Fatalf("err = %v", err)
+	}
+	if got := f.stmts[n:]; !reflect.DeepEqual(got, []string{"BEGIN", "ROLLBACK"}) {
+		t.Fatalf("stmts = %v", got)
+	}
+
+	f.columns = []string{"id", "name"}
+	f.rows = [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}
+	list, err := Fetch[TestHooked](db.SELECT(TestHooked{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list[0].Name != "found:a" || list[1].Name != "found:b" {
+		t.Fatalf("list = %+v", list)
+	}
+}
+
+func TestHooksReturning(t *testing.T) {
+	db, f := newFakeDB(t)
+	var calls []string
+	f.columns = []string{"id", "name"}
+	f.rows = [][]driver.Value{{int64(1), "default"}}
+	var got TestHooked
+	if err := db.INSERT(TestHooked{ID: 1, calls: &calls}).RETURNING(&got, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []driver.Value{int64(1), "default"}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+	var list []TestHooked
+	if err := db.UPDATE(TestHooked{ID: 1, Name: "b", calls: &calls}).WherePK().RETURNING(nil, &list, "id"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"BeforeInsert", "AfterInsert", "BeforeUpdate", "AfterUpdate"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v", calls)
+	}
+
+	n := len(f.stmts)
+	err := db.INSERT(TestHooked{ID: 2, Name: "abort"}).RETURNING(&got, nil)
+	if !errors.Is(err, errHookAbort) || len(f.stmts) != n {
+		t.Fatalf("err = %v, stmts = %v", err, f.stmts[n:])
+	}
+}
@@ -0,0 +1,99 @@
+package mworm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Iter 逐行读取查询结果，适用于大结果集，使用完毕后必须调用 Close
+//
+//	it := mworm.NewIter[User](mworm.SELECT(User{}))
+//	defer it.Close()
+//	for it.Next() {
+//		user := it.Value()
+//	}
+//	err := it.Err()
+type Iter[T any] struct {
+	ctx   context.Context
+	rows  *sqlx.Rows
+	sc    *rowScanner
+	value T
+	err   error
+}
+
+// NewIter 执行 o 并返回逐行读取结果的 Iter
+func NewIter[T any](o *OrmModel) *Iter[T] {
+	it := &Iter[T]{ctx: o.context()}
+	if o.method != methodSelect && len(o.returning) == 0 && !o.rawSQL {
+		it.err = errors.New(`o.method must be [methodSelect]`)
+		return it
+	}
+	it.rows, it.err = o.queryRows()
+	return it
+}
+
+// Next 读取下一行，没有更多记录或出错时返回 false 并关闭结果集
+func (it *Iter[T]) Next() bool {
+	if it.err != nil || it.rows == nil {
+		return false
+	}
+	if !it.rows.Next() {
+		it.err = it.rows.Err()
+		_ = it.Close()
+		return false
+	}
+	var value T
+	v := reflect.ValueOf(&value).Elem()
+	if v.Kind() == reflect.Ptr {
+		// T 为指针时映射到新分配的结构体
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	if it.sc == nil {
+		if it.sc, it.err = newRowScanner(it.ctx, it.rows, v.Type()); it.err != nil {
+			_ = it.Close()
+			return false
+		}
+	}
+	if it.err = it.sc.scan(it.rows, v); it.err != nil {
+		_ = it.Close()
+		return false
+	}
+	it.value = value
+	return true
+}
+
+// Value 返回当前行
+func (it *Iter[T]) Value() T {
+	return it.value
+}
+
+// Err 返回查询或映射过程中的错误
+func (it *Iter[T]) Err() error {
+	return it.err
+}
+
+// Close 关闭结果集，可重复调用
+func (it *Iter[T]) Close() error {
+	if it.rows == nil {
+		return nil
+	}
+	err := it.rows.Close()
+	it.rows = nil
+	return err
+}
+
+// Each 执行 o 并逐行调用 fn，fn 返回错误时停止并返回该错误，结果集始终会被关闭
+func Each[T any](o *OrmModel, fn func(row T) error) error {
+	it := NewIter[T](o)
+	defer func() { _ = it.Close() }()
+	for it.Next() {
+		if err := fn(it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,28 @@
+//go:build go1.23
+
+package mworm
+
+import "iter"
+
+// All 返回 iter.Seq2，可使用 for row, err := range it.All() 遍历，出错时最后一次返回错误
+//
+// 提前 break 或遍历结束后结果集会被关闭
+func (it *Iter[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer func() { _ = it.Close() }()
+		for it.Next() {
+			if !yield(it.Value(), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// Seq 执行 o 并返回 iter.Seq2
+func Seq[T any](o *OrmModel) iter.Seq2[T, error] {
+	return NewIter[T](o).All()
+}
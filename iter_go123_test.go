@@ -0,0 +1,29 @@
+//go:build go1.23
+
+package mworm
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestIterAll(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"id", "name"}
+	f.rows = [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}
+
+	var ids []int
+	for row, err := range Seq[TestTable](db.SELECT(TestTable{})) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, row.ID)
+		break
+	}
+	if len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("ids = %v", ids)
+	}
+	if open := f.openRows(); open != 0 {
+		t.Fatalf("open rows = %d", open)
+	}
+}
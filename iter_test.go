@@ -0,0 +1,49 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestEach(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"id", "name"}
+	f.rows = [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}}
+
+	var names []string
+	err := Each(db.SELECT(TestTable{}), func(row TestTable) error {
+		names = append(names, row.Name)
+		return nil
+	})
+	if err != nil || len(names) != 3 {
+		t.Fatalf("names = %v, err = %v", names, err)
+	}
+
+	errStop := errors.New("stop")
+	var n int
+	err = Each(db.SELECT(TestTable{}), func(row *TestTable) error {
+		n++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || n != 1 {
+		t.Fatalf("n = %d, err = %v", n, err)
+	}
+
+	f.columns, f.rows = []string{"id"}, [][]driver.Value{{int64(1)}, {int64(2)}, {int64(3)}}
+	it := NewIter[int64](db.RawSQL(`SELECT id FROM test_table`))
+	var sum int64
+	for it.Next() {
+		sum += it.Value()
+	}
+	if it.Err() != nil || sum != 6 {
+		t.Fatalf("sum = %d, err = %v", sum, it.Err())
+	}
+	if open := f.openRows(); open != 0 {
+		t.Fatalf("open rows = %d", open)
+	}
+
+	if err = Each(db.UPDATE(TestTable{}), func(TestTable) error { return nil }); err == nil {
+		t.Fatal("Each on UPDATE should return error")
+	}
+}
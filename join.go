@@ -12,8 +12,13 @@ const (
 	InnerJoin JoinType = iota // INNER JOIN
 	LeftJoin                  // LEFT JOIN
 	RightJoin                 // RIGHT JOIN
+	FullJoin                  // FULL JOIN，MySQL 不支持
+	CrossJoin                 // CROSS JOIN
 )
 
+// joinMainAlias JOIN 查询时主表的别名
+const joinMainAlias = "t"
+
 // String 返回 JOIN 类型的字符串表示
 func (j JoinType) String() string {
 	switch j {
@@ -23,6 +28,10 @@ func (j JoinType) String() string {
 		return "LEFT JOIN"
 	case RightJoin:
 		return "RIGHT JOIN"
+	case FullJoin:
+		return "FULL JOIN"
+	case CrossJoin:
+		return "CROSS JOIN"
 	default:
 		return "INNER JOIN"
 	}
@@ -30,26 +39,132 @@ func (j JoinType) String() string {
 
 // JoinTable 连接表的结构
 type JoinTable struct {
-	Type        JoinType         // JOIN 类型
-	Table       string           // 表名
-	Alias       string           // 表别名
-	Conditions  []ConditionGroup // JOIN 条件
-	SelectField []string         // 需要查询的字段
+	Type        JoinType          // JOIN 类型
+	Table       string            // 表名
+	Alias       string            // 表别名
+	Conditions  []ConditionGroup  // JOIN 条件
+	SelectField []string          // 需要查询的字段 column，为空时查询全部字段
+	dbFields    map[string]string // 连接表字段 json:column
+	fieldOrder  []string          // 连接表字段顺序 json
+}
+
+// Join INNER JOIN 连接 i，alias 为连接表别名，主表别名固定为 t
+//
+// 连接表的字段以 alias_column 作为列名返回，组合结构体使用该名称作为 db tag
+func (o *OrmModel) Join(i ORMInterface, alias string, on ...ConditionGroup) *OrmModel {
+	return o.addJoin(InnerJoin, i, alias, on)
+}
+
+// LeftJoin LEFT JOIN 连接 i
+func (o *OrmModel) LeftJoin(i ORMInterface, alias string, on ...ConditionGroup) *OrmModel {
+	return o.addJoin(LeftJoin, i, alias, on)
+}
+
+// RightJoin RIGHT JOIN 连接 i
+func (o *OrmModel) RightJoin(i ORMInterface, alias string, on ...ConditionGroup) *OrmModel {
+	return o.addJoin(RightJoin, i, alias, on)
+}
+
+// FullJoin FULL JOIN 连接 i
+func (o *OrmModel) FullJoin(i ORMInterface, alias string, on ...ConditionGroup) *OrmModel {
+	return o.addJoin(FullJoin, i, alias, on)
 }
 
-// Join 添加连接表
-func (o *OrmModel) Join(i ORMInterface) *OrmModel {
+// CrossJoin CROSS JOIN 连接 i
+func (o *OrmModel) CrossJoin(i ORMInterface, alias string) *OrmModel {
+	return o.addJoin(CrossJoin, i, alias, nil)
+}
+
+func (o *OrmModel) addJoin(joinType JoinType, i ORMInterface, alias string, on []ConditionGroup) *OrmModel {
 	if o.method != methodSelect {
 		o.err = fmt.Errorf("JOIN only supports SELECT")
 		return o
 	}
-	//if o.joinTables == nil {
-	//	o.joinTables = make([]*JoinTable, 0)
-	//}
-	//o.joinTables = append(o.joinTables, joinTable)
+	if len(alias) == 0 || alias == joinMainAlias {
+		o.err = fmt.Errorf("invalid JOIN alias %q", alias)
+		return o
+	}
+	jo := new(OrmModel)
+	jo.init()
+	jo.structToMap(i)
+	o.joinTables = append(o.joinTables, &JoinTable{
+		Type:       joinType,
+		Table:      o.dialect().Quote(i.TableName()),
+		Alias:      alias,
+		Conditions: on,
+		dbFields:   jo.dbFields,
+		fieldOrder: jo.fieldOrder,
+	})
 	return o
 }
 
+// On JOIN ON 条件 主表字段=连接表字段，参数为 json tag，可使用 alias.jsonTag 指定其他表的字段
+func On(tag, joinTag string) ConditionGroup {
+	return ConditionGroup{
+		JsonTags: []string{tag, joinTag},
+		Symbol:   "=",
+		cType:    cgTypeJoinOn,
+	}
+}
+
+// JoinOn 创建 JOIN ON 条件
+func JoinOn(express string) ConditionGroup {
+	return ConditionGroup{
+		Express: express,
+		cType:   cgTypeNamedExpress,
+	}
+}
+
+// SelectFields 设置需要查询的字段
+func (j *JoinTable) SelectFields(fields ...string) *JoinTable {
+	j.SelectField = fields
+	return j
+}
+
+// fromSQL FROM 之后的表名，JOIN 查询时包含主表别名与 JOIN 子句
+func (o *OrmModel) fromSQL() string {
+	if len(o.joinTables) == 0 {
+		return o.tableName
+	}
+	return o.tableName + ` ` + joinMainAlias + o.parseJoinSQL()
+}
+
+// qualify JOIN 查询时为主表的列加上别名 t
+func (o *OrmModel) qualify(column string) string {
+	if len(o.joinTables) == 0 || len(column) == 0 || strings.Contains(column, ".") {
+		return column
+	}
+	return joinMainAlias + `.` + column
+}
+
+// joinColumn 连接表 join 中 json tag 对应的列，alias.jsonTag 时按别名查找
+func (o *OrmModel) joinColumn(join *JoinTable, tag string) string {
+	if strings.Contains(tag, ".") {
+		return o.qualify(o.columnField(tag))
+	}
+	if column := join.dbFields[tag]; len(column) > 0 {
+		return join.Alias + `.` + column
+	}
+	return ""
+}
+
+// joinSelectFields 连接表查询的字段，以 alias_column 命名
+func (o *OrmModel) joinSelectFields() []string {
+	var fieldArr []string
+	for _, join := range o.joinTables {
+		columns := join.SelectField
+		if len(columns) == 0 {
+			for _, j := range orderedKeys(join.fieldOrder, join.dbFields) {
+				columns = append(columns, join.dbFields[j])
+			}
+		}
+		for _, column := range columns {
+			fieldArr = append(fieldArr, fmt.Sprintf(`%s.%s AS %s_%s`, join.Alias, column, join.Alias, column))
+		}
+	}
+	return fieldArr
+}
+
 // parseJoinSQL 解析 JOIN SQL
 func (o *OrmModel) parseJoinSQL() string {
 	if len(o.joinTables) == 0 {
@@ -59,46 +174,46 @@ func (o *OrmModel) parseJoinSQL() string {
 	var joinSQL strings.Builder
 	for _, join := range o.joinTables {
 		// 构建 JOIN 子句
-		joinSQL.WriteString(fmt.Sprintf(" %s %s", join.Type.String(), join.Table))
-		if join.Alias != "" {
-			joinSQL.WriteString(fmt.Sprintf(" AS %s", join.Alias))
+		joinSQL.WriteString(fmt.Sprintf(" %s %s AS %s", join.Type.String(), join.Table, join.Alias))
+		if join.Type == CrossJoin {
+			continue
 		}
 
 		// 构建 ON 条件
-		if len(join.Conditions) > 0 {
-			joinSQL.WriteString(" ON ")
-			var conditions []string
-			for _, cond := range join.Conditions {
-				switch cond.cType {
-				case cgTypeAndOr, cgTypeAndOrAutoRemove:
-					for i, tag := range cond.JsonTags {
-						if i > 0 {
-							joinSQL.WriteString(cond.Logic)
-						}
-						conditions = append(conditions, fmt.Sprintf("%s = %s", tag, o.columnField(tag)))
+		var conditions []string
+		for _, cond := range join.Conditions {
+			switch cond.cType {
+			case cgTypeJoinOn:
+				left, right := o.qualify(o.columnField(cond.JsonTags[0])), o.joinColumn(join, cond.JsonTags[1])
+				if len(left) == 0 || len(right) == 0 {
+					o.err = fmt.Errorf("invalid JOIN ON %s=%s", cond.JsonTags[0], cond.JsonTags[1])
+					continue
+				}
+				conditions = append(conditions, left+cond.Symbol+right)
+			case cgTypeAndOr, cgTypeAndOrAutoRemove:
+				// 两表中同名字段相等
+				var names []string
+				for _, tag := range cond.JsonTags {
+					left, right := o.qualify(o.columnField(tag)), o.joinColumn(join, tag)
+					if len(left) == 0 || len(right) == 0 {
+						o.err = fmt.Errorf("invalid JOIN ON %s", tag)
+						continue
 					}
-				case cgTypeNamedExpress:
-					conditions = append(conditions, cond.Express)
-				default:
-					panic("unhandled default case")
+					names = append(names, left+`=`+right)
 				}
+				if len(names) > 0 {
+					conditions = append(conditions, `(`+strings.Join(names, cond.Logic)+`)`)
+				}
+			case cgTypeNamedExpress, cgTypeRaw:
+				conditions = append(conditions, `(`+o.bindRaw(cond.Express, cond.Args)+`)`)
+			default:
+				o.err = fmt.Errorf("unsupported JOIN ON condition")
 			}
+		}
+		if len(conditions) > 0 {
+			joinSQL.WriteString(" ON ")
 			joinSQL.WriteString(strings.Join(conditions, " AND "))
 		}
 	}
 	return joinSQL.String()
 }
-
-// JoinOn 创建 JOIN ON 条件
-func JoinOn(express string) ConditionGroup {
-	return ConditionGroup{
-		Express: express,
-		cType:   cgTypeNamedExpress,
-	}
-}
-
-// SelectFields 设置需要查询的字段
-func (j *JoinTable) SelectFields(fields ...string) *JoinTable {
-	j.SelectField = fields
-	return j
-}
@@ -0,0 +1,64 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type TestOrder struct {
+	ID      int    `json:"id" db:"id,pk"`
+	TableID int    `json:"tableId" db:"table_id"`
+	OrderNo string `json:"orderNo" db:"order_no"`
+}
+
+func (TestOrder) TableName() string { return "test_order" }
+
+type TestTableOrder struct {
+	ID      int    `json:"id" db:"id"`
+	Name    string `json:"name" db:"name"`
+	OrderID int    `json:"orderId" db:"o_id"`
+	OrderNo string `json:"orderNo" db:"o_order_no"`
+}
+
+func TestJoin(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"id", "name", "o_id", "o_order_no"}
+	f.rows = [][]driver.Value{{int64(1), "a", int64(7), "N7"}, {int64(1), "a", int64(8), "N8"}}
+
+	var list []TestTableOrder
+	err := db.SELECT(TestTable{Name: "a"}).LeftJoin(TestOrder{}, "o", On("id", "tableId")).
+		Where(And("name"), Eq("o.orderNo", "N%"), IN("o.id", 7, 8)).Desc("o.id").Many(&list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT  t.*, o.id AS o_id, o.table_id AS o_table_id, o.order_no AS o_order_no FROM "test_table" t` +
+		` LEFT JOIN "test_order" AS o ON t.id=o.table_id WHERE (t.name=$1) AND o.order_no=$2 AND o.id IN ($3,$4)` +
+		` ORDER BY o.id DESC`
+	if got := f.statements(); len(got) != 1 || got[0] != want {
+		t.Fatalf("statements = %q", got)
+	}
+	if want := []driver.Value{"a", "N%", int64(7), int64(8)}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %v", f.args[0])
+	}
+	wantList := []TestTableOrder{{1, "a", 7, "N7"}, {1, "a", 8, "N8"}}
+	if !reflect.DeepEqual(list, wantList) {
+		t.Fatalf("list = %+v", list)
+	}
+
+	sp := db.SELECT(TestTable{}).Join(TestOrder{}, "o", On("id", "tableId"), Raw(`o.order_no<>$1`, "")).
+		CrossJoin(TestOrder{}, "c").Fields("name").BuildSQL()
+	want = `SELECT  t.name, o.id AS o_id, o.table_id AS o_table_id, o.order_no AS o_order_no,` +
+		` c.id AS c_id, c.table_id AS c_table_id, c.order_no AS c_order_no FROM "test_table" t` +
+		` INNER JOIN "test_order" AS o ON t.id=o.table_id AND (o.order_no<>$1) CROSS JOIN "test_order" AS c`
+	if sp.Sql != want {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+
+	if err := db.SELECT(TestTable{}).Join(TestOrder{}, "o", On("id", "missing")).Many(&list); err == nil {
+		t.Fatal("invalid ON should return error")
+	}
+	if err := db.UPDATE(TestTable{}).Join(TestOrder{}, "o").Error(); err == nil {
+		t.Fatal("JOIN on UPDATE should return error")
+	}
+}
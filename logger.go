@@ -0,0 +1,87 @@
+package mworm
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Logger 记录 SQL 执行，level 由 LogConfig 按执行结果决定
+type Logger interface {
+	LogSQL(ctx context.Context, level zerolog.Level, stmt *Statement)
+}
+
+// LogConfig SQL 日志配置
+//
+// 出错的 SQL 按 Error 记录（SkipErrors 为 true 时不记录），耗时超过 SlowThreshold 的按 Warn 记录；
+// 其余 SQL 在 All 为 true、调用了 Log(true) 或开启 DebugMode 时按 Level 记录
+type LogConfig struct {
+	Logger        Logger        // 为 nil 时使用 zerolog 全局 log.Logger
+	Level         zerolog.Level // 正常 SQL 的级别，默认 Debug
+	All           bool          // 记录全部 SQL
+	SlowThreshold time.Duration // 慢查询阈值，0 表示不检测
+	SkipErrors    bool          // 不记录出错的 SQL，由调用方处理返回的错误
+	HideArgs      bool          // 不记录绑定参数，避免密码等敏感数据写入日志
+}
+
+var logConfig LogConfig
+
+// SetLogConfig 设置 SQL 日志配置，应在初始化时调用
+func SetLogConfig(cfg LogConfig) {
+	logConfig = cfg
+}
+
+// ZeroLogger 使用 zerolog 输出的 Logger
+type ZeroLogger struct {
+	Logger zerolog.Logger
+}
+
+// LogSQL 输出操作类型、表名、SQL、参数、耗时、影响行数及错误，Args 为 nil 时不输出参数
+func (l ZeroLogger) LogSQL(_ context.Context, level zerolog.Level, stmt *Statement) {
+	msg := "sql"
+	if level == zerolog.WarnLevel && stmt.Err == nil {
+		msg = "slow sql"
+	}
+	e := l.Logger.WithLevel(level).
+		Str("operation", stmt.Operation).
+		Str("table", stmt.Table).
+		Str("sql", stmt.SQL)
+	if stmt.Args != nil {
+		e = e.Interface("args", stmt.Args)
+	}
+	e.Dur("duration", stmt.Duration).
+		Int64("rows", stmt.RowsAffected).
+		Err(stmt.Err).
+		Msg(msg)
+}
+
+// logStatement 按 logConfig 记录 stmt，force 为 true 时总是记录
+func logStatement(ctx context.Context, stmt *Statement, force bool) {
+	cfg := logConfig
+	var level zerolog.Level
+	switch {
+	case stmt.Err != nil:
+		if cfg.SkipErrors {
+			return
+		}
+		level = zerolog.ErrorLevel
+	case cfg.SlowThreshold > 0 && stmt.Duration >= cfg.SlowThreshold:
+		level = zerolog.WarnLevel
+	case cfg.All || force || DebugMode:
+		level = cfg.Level
+	default:
+		return
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = ZeroLogger{Logger: log.Logger}
+	}
+	if cfg.HideArgs {
+		hidden := *stmt
+		hidden.Args = nil
+		stmt = &hidden
+	}
+	logger.LogSQL(ctx, level, stmt)
+}
@@ -0,0 +1,77 @@
+package mworm
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type testLogger struct {
+	levels []zerolog.Level
+	stmts  []Statement
+}
+
+func (l *testLogger) LogSQL(_ context.Context, level zerolog.Level, stmt *Statement) {
+	l.levels = append(l.levels, level)
+	l.stmts = append(l.stmts, *stmt)
+}
+
+func TestLogger(t *testing.T) {
+	db, f := newFakeDB(t)
+	l := new(testLogger)
+	SetLogConfig(LogConfig{Logger: l, Level: zerolog.InfoLevel})
+	t.Cleanup(func() { SetLogConfig(LogConfig{}) })
+
+	_ = db.INSERT(TestTable{ID: 1}).Exec()
+	_ = db.INSERT(TestTable{ID: 2}).Log(true).Exec()
+	f.failOn = "UPDATE"
+	_ = db.UPDATE(TestTable{ID: 3, Name: "a"}).WherePK().Exec()
+	if want := []zerolog.Level{zerolog.InfoLevel, zerolog.ErrorLevel}; !reflect.DeepEqual(l.levels, want) {
+		t.Fatalf("levels = %v", l.levels)
+	}
+	if s := l.stmts[1]; s.Operation != methodUpdate || s.Table != "test_table" || s.Err == nil {
+		t.Fatalf("stmt = %+v", s)
+	}
+
+	// 慢查询
+	f.failOn = ""
+	db.Use(func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) error {
+			err := next(ctx, stmt)
+			stmt.Duration = time.Second
+			return err
+		}
+	})
+	var buf bytes.Buffer
+	SetLogConfig(LogConfig{Logger: ZeroLogger{Logger: zerolog.New(&buf)}, SlowThreshold: 100 * time.Millisecond})
+	_ = db.INSERT(TestTable{ID: 4, Name: "b"}).Exec()
+	out := buf.String()
+	for _, s := range []string{`"level":"warn"`, `"message":"slow sql"`, `"table":"test_table"`, `"rows":1`, `"args":[4,"b"]`} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("log = %s, missing %s", out, s)
+		}
+	}
+
+	// 隐藏参数，不记录出错的 SQL
+	buf.Reset()
+	SetLogConfig(LogConfig{Logger: ZeroLogger{Logger: zerolog.New(&buf)}, All: true, HideArgs: true})
+	_ = db.INSERT(TestTable{ID: 5, Name: "secret"}).Exec()
+	f.failOn = "UPDATE"
+	_ = db.UPDATE(TestTable{ID: 6, Name: "secret"}).WherePK().Exec()
+	if out = buf.String(); strings.Contains(out, "secret") || strings.Contains(out, `"args"`) || !strings.Contains(out, `"level":"error"`) {
+		t.Fatalf("log = %s", out)
+	}
+	l = new(testLogger)
+	SetLogConfig(LogConfig{Logger: l, SkipErrors: true, HideArgs: true})
+	_ = db.UPDATE(TestTable{ID: 6, Name: "secret"}).WherePK().Exec()
+	f.failOn = ""
+	_ = db.INSERT(TestTable{ID: 7, Name: "secret"}).Log(true).Exec()
+	if len(l.stmts) != 1 || l.stmts[0].Args != nil || l.stmts[0].Err != nil {
+		t.Fatalf("stmts = %+v", l.stmts)
+	}
+}
@@ -0,0 +1,117 @@
+package mworm
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MetricsCollector 收集 SQL 指标，table 为 ORMInterface.TableName，原生 SQL 为空
+//
+// 可适配 Prometheus：查询数与错误数使用 CounterVec，耗时使用 HistogramVec，标签为 table、operation
+type MetricsCollector interface {
+	ObserveStatement(table, operation string, duration time.Duration, err error)
+}
+
+// MetricsMiddleware 返回将每条 SQL 的表名、操作类型、耗时及错误交给 c 的中间件
+//
+//	mworm.Use(mworm.MetricsMiddleware(collector))
+func MetricsMiddleware(c MetricsCollector) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) error {
+			err := next(ctx, stmt)
+			c.ObserveStatement(stmt.Table, stmt.Operation, stmt.Duration, err)
+			return err
+		}
+	}
+}
+
+// DefaultLatencyBuckets NewMemoryMetrics 默认的耗时直方图上界
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+// MetricsKey 指标标签
+type MetricsKey struct {
+	Table     string
+	Operation string
+}
+
+// LatencyHistogram 耗时直方图，与 Prometheus Histogram 相同，Counts[i] 为耗时不超过 Buckets[i] 的累计次数
+type LatencyHistogram struct {
+	Buckets []time.Duration // 上界，升序
+	Counts  []int64         // 与 Buckets 对应的累计次数
+	Count   int64           // 总次数，包括超过最大上界的
+	Sum     time.Duration   // 总耗时
+}
+
+func (h *LatencyHistogram) observe(d time.Duration) {
+	for i := sort.Search(len(h.Buckets), func(i int) bool { return d <= h.Buckets[i] }); i < len(h.Buckets); i++ {
+		h.Counts[i]++
+	}
+	h.Count++
+	h.Sum += d
+}
+
+// StatementMetrics 一组 table、operation 的查询数、错误数与耗时
+type StatementMetrics struct {
+	Queries int64
+	Errors  int64
+	Latency LatencyHistogram
+}
+
+// MemoryMetrics 内存中的 MetricsCollector，按 table、operation 统计，并发安全
+type MemoryMetrics struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	stats   map[MetricsKey]*StatementMetrics
+}
+
+// NewMemoryMetrics 创建 MemoryMetrics，buckets 为耗时直方图上界，为空时使用 DefaultLatencyBuckets
+func NewMemoryMetrics(buckets ...time.Duration) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return &MemoryMetrics{buckets: buckets, stats: map[MetricsKey]*StatementMetrics{}}
+}
+
+// ObserveStatement 实现 MetricsCollector
+func (m *MemoryMetrics) ObserveStatement(table, operation string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := MetricsKey{Table: table, Operation: operation}
+	s, ok := m.stats[key]
+	if !ok {
+		s = &StatementMetrics{Latency: LatencyHistogram{Buckets: m.buckets, Counts: make([]int64, len(m.buckets))}}
+		m.stats[key] = s
+	}
+	s.Queries++
+	if err != nil {
+		s.Errors++
+	}
+	s.Latency.observe(duration)
+}
+
+// Snapshot 返回当前指标的副本
+func (m *MemoryMetrics) Snapshot() map[MetricsKey]StatementMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[MetricsKey]StatementMetrics, len(m.stats))
+	for key, s := range m.stats {
+		c := *s
+		c.Latency.Counts = append([]int64(nil), s.Latency.Counts...)
+		result[key] = c
+	}
+	return result
+}
+
+// Reset 清空已收集的指标
+func (m *MemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = map[MetricsKey]*StatementMetrics{}
+}
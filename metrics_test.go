@@ -0,0 +1,72 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	db, f := newFakeDB(t)
+	m := NewMemoryMetrics()
+	db.Use(MetricsMiddleware(m))
+
+	_ = db.INSERT(TestTable{ID: 1}).Exec()
+	_ = db.INSERT(TestTable{ID: 2}).Exec()
+	f.columns = []string{"id"}
+	f.rows = [][]driver.Value{{int64(1)}}
+	var list []TestUser
+	_ = db.SELECT(TestUser{}).Many(&list)
+	f.failOn = "UPDATE"
+	_ = db.UPDATE(TestTable{ID: 1, Name: "a"}).WherePK().Exec()
+	_ = db.Exec(`UPDATE test_table SET name=$1`, "b")
+
+	stats := m.Snapshot()
+	queries, errs := map[MetricsKey]int64{}, map[MetricsKey]int64{}
+	for key, s := range stats {
+		queries[key] = s.Queries
+		if s.Errors > 0 {
+			errs[key] = s.Errors
+		}
+	}
+	wantQueries := map[MetricsKey]int64{
+		{"test_table", methodInsert}: 2,
+		{"c_user", methodSelect}:     1,
+		{"test_table", methodUpdate}: 1,
+		{"", methodUpdate}:           1,
+	}
+	if !reflect.DeepEqual(queries, wantQueries) {
+		t.Fatalf("queries = %v", queries)
+	}
+	wantErrors := map[MetricsKey]int64{{"test_table", methodUpdate}: 1, {"", methodUpdate}: 1}
+	if !reflect.DeepEqual(errs, wantErrors) {
+		t.Fatalf("errors = %v", errs)
+	}
+	if h := stats[MetricsKey{"test_table", methodInsert}].Latency; h.Count != 2 || h.Sum <= 0 ||
+		len(h.Counts) != len(DefaultLatencyBuckets) {
+		t.Fatalf("latency = %+v", h)
+	}
+
+	m.Reset()
+	if n := len(m.Snapshot()); n != 0 {
+		t.Fatalf("stats after reset = %d", n)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	m := NewMemoryMetrics(10*time.Millisecond, time.Millisecond)
+	for _, d := range []time.Duration{time.Millisecond, 5 * time.Millisecond, 20 * time.Millisecond} {
+		m.ObserveStatement("t", methodSelect, d, nil)
+	}
+	h := m.Snapshot()[MetricsKey{"t", methodSelect}].Latency
+	want := LatencyHistogram{
+		Buckets: []time.Duration{time.Millisecond, 10 * time.Millisecond},
+		Counts:  []int64{1, 2},
+		Count:   3,
+		Sum:     26 * time.Millisecond,
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Fatalf("histogram = %+v", h)
+	}
+}
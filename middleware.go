@@ -0,0 +1,160 @@
+package mworm
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Statement 一次 SQL 执行，中间件可在调用 next 前修改 SQL 与 Args，调用后读取执行结果
+type Statement struct {
+	Operation    string        // SELECT/INSERT/UPDATE/DELETE，原生 SQL 为其第一个关键字
+	Table        string        // 表名，原生 SQL 为空
+	SQL          string        // 最终执行的 SQL
+	Args         []any         // 绑定参数
+	Duration     time.Duration // 执行耗时，查询不包含读取结果集的时间
+	RowsAffected int64         // INSERT/UPDATE/DELETE 的影响行数
+	Err          error         // 执行错误
+}
+
+// Handler 执行 Statement
+type Handler func(ctx context.Context, stmt *Statement) error
+
+// Middleware 包装 Handler，可用于日志、监控、链路追踪及改写 SQL
+//
+//	mworm.Use(func(next mworm.Handler) mworm.Handler {
+//		return func(ctx context.Context, stmt *mworm.Statement) error {
+//			err := next(ctx, stmt)
+//			fmt.Println(stmt.SQL, stmt.Duration, err)
+//			return err
+//		}
+//	})
+type Middleware func(next Handler) Handler
+
+// errNotExecuted 中间件未调用 next 且未返回错误
+var errNotExecuted = errors.New("statement was not executed by middleware")
+
+// middlewares 全局中间件
+var middlewares []Middleware
+
+// Use 添加全局中间件，对所有 DB 生效，先添加的在外层，应在初始化时调用
+func Use(mw ...Middleware) {
+	middlewares = append(middlewares, mw...)
+}
+
+// Use 添加只对 d 及其事务生效的中间件，位于全局中间件内层，应在初始化时调用
+func (d *DB) Use(mw ...Middleware) *DB {
+	d.middlewares = append(d.middlewares, mw...)
+	return d
+}
+
+// interceptor 包装 sqlx.ExtContext，执行 SQL 时经过中间件
+type interceptor struct {
+	sqlx.ExtContext
+	db        *DB
+	operation string
+	table     string
+	log       bool
+}
+
+// intercept 返回经过中间件执行 SQL 的 e，operation 为空时取 SQL 的第一个关键字
+func (d *DB) intercept(e sqlx.ExtContext, operation, table string, log bool) sqlx.ExtContext {
+	return &interceptor{ExtContext: e, db: d, operation: operation, table: table, log: log}
+}
+
+// intercept 返回按 o 的操作类型与表名经过中间件执行 SQL 的 e
+func (o *OrmModel) intercept(e sqlx.ExtContext) sqlx.ExtContext {
+	operation := o.method
+	if o.rawSQL {
+		operation = ""
+	}
+	return o.database().intercept(e, operation, o.table, o.log)
+}
+
+func (c *interceptor) run(ctx context.Context, query string, args []any, exec Handler) error {
+	stmt := &Statement{Operation: c.operation, Table: c.table, SQL: query, Args: args}
+	if len(stmt.Operation) == 0 {
+		stmt.Operation = sqlOperation(query)
+	}
+	executed := false
+	h := Handler(func(ctx context.Context, stmt *Statement) error {
+		executed = true
+		start := time.Now()
+		err := exec(ctx, stmt)
+		stmt.Duration, stmt.Err = time.Since(start), err
+		return err
+	})
+	chain := append(middlewares[:len(middlewares):len(middlewares)], c.db.middlewares...)
+	for i := len(chain) - 1; i >= 0; i-- {
+		// 每层返回后记录错误，外层中间件可从 stmt.Err 读取
+		next := chain[i](h)
+		h = func(ctx context.Context, stmt *Statement) error {
+			stmt.Err = next(ctx, stmt)
+			return stmt.Err
+		}
+	}
+	err := h(ctx, stmt)
+	if !executed && err == nil {
+		// 中间件未调用 next
+		err = errNotExecuted
+		stmt.Err = err
+	}
+	traceStatement(ctx, stmt)
+	logStatement(ctx, stmt, c.log)
+	return err
+}
+
+func (c *interceptor) ExecContext(ctx context.Context, query string, args ...any) (dbsql.Result, error) {
+	var result dbsql.Result
+	err := c.run(ctx, query, args, func(ctx context.Context, stmt *Statement) error {
+		var err error
+		if result, err = c.ExtContext.ExecContext(ctx, stmt.SQL, stmt.Args...); err == nil {
+			stmt.RowsAffected, _ = result.RowsAffected()
+		}
+		return err
+	})
+	return result, err
+}
+
+func (c *interceptor) QueryContext(ctx context.Context, query string, args ...any) (*dbsql.Rows, error) {
+	var rows *dbsql.Rows
+	err := c.run(ctx, query, args, func(ctx context.Context, stmt *Statement) error {
+		var err error
+		rows, err = c.ExtContext.QueryContext(ctx, stmt.SQL, stmt.Args...)
+		return err
+	})
+	return rows, err
+}
+
+func (c *interceptor) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	err := c.run(ctx, query, args, func(ctx context.Context, stmt *Statement) error {
+		var err error
+		rows, err = c.ExtContext.QueryxContext(ctx, stmt.SQL, stmt.Args...)
+		return err
+	})
+	return rows, err
+}
+
+// sqlOperation SQL 的第一个关键字
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimLeft(fields[0], "("))
+}
+
+// ext 返回经过中间件执行原生 SQL 的连接
+func (d *DB) ext() sqlx.ExtContext {
+	return d.intercept(d.sqlxDB, "", "", false)
+}
+
+// ext 返回经过中间件执行原生 SQL 的事务
+func (tx *Tx) ext() sqlx.ExtContext {
+	return tx.db.intercept(tx.sqlxTx, "", "", false)
+}
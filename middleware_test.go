@@ -0,0 +1,104 @@
+package mworm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	db, f := newFakeDB(t)
+	var order []string
+	var stmts []Statement
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, stmt *Statement) error {
+				order = append(order, name)
+				err := next(ctx, stmt)
+				if name == "global" {
+					stmts = append(stmts, *stmt)
+				}
+				return err
+			}
+		}
+	}
+	Use(record("global"))
+	t.Cleanup(func() { middlewares = nil })
+	db.Use(record("db"))
+
+	if err := db.INSERT(TestTable{ID: 1, Name: "a"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []string{"global", "db"}) {
+		t.Fatalf("order = %v", order)
+	}
+	s := stmts[0]
+	if s.Operation != methodInsert || s.Table != "test_table" || s.SQL != f.stmts[0] || s.RowsAffected != 1 ||
+		s.Err != nil || s.Duration <= 0 || !reflect.DeepEqual(s.Args, []any{1, "a"}) {
+		t.Fatalf("stmt = %+v", s)
+	}
+
+	f.columns = []string{"id"}
+	f.rows = [][]driver.Value{{int64(1)}}
+	var list []TestTable
+	if err := db.SELECT(TestTable{}).Many(&list); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.BatchFunc(func(tx *Tx) error {
+		return tx.Exec(`DELETE FROM test_table WHERE id=$1`, 1)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stmts[len(stmts)-2]; got.Operation != methodSelect || got.Table != "test_table" {
+		t.Fatalf("stmt = %+v", got)
+	}
+	if got := stmts[len(stmts)-1]; got.Operation != methodDelete || got.Table != "" {
+		t.Fatalf("stmt = %+v", got)
+	}
+
+	// 改写 SQL 与返回错误
+	errDenied := errors.New("denied")
+	db.Use(func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) error {
+			if stmt.Operation == methodDelete {
+				return errDenied
+			}
+			stmt.SQL += ` /* app */`
+			return next(ctx, stmt)
+		}
+	})
+	if err := db.UPDATE(TestTable{ID: 1, Name: "b"}).WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "test_table" SET name=$1 WHERE (id=$2) /* app */`; f.stmts[len(f.stmts)-1] != want {
+		t.Fatalf("sql = %s", f.stmts[len(f.stmts)-1])
+	}
+	if err := db.DELETE(TestTable{ID: 1}).WherePK().Exec(); !errors.Is(err, errDenied) {
+		t.Fatalf("err = %v", err)
+	}
+	if got := stmts[len(stmts)-1]; !errors.Is(got.Err, errDenied) {
+		t.Fatalf("stmt = %+v", got)
+	}
+}
+
+func TestMiddlewareSkipNext(t *testing.T) {
+	db, f := newFakeDB(t)
+	db.Use(func(next Handler) Handler {
+		return func(ctx context.Context, stmt *Statement) error { return nil }
+	})
+	if err := db.UPDATE(TestTable{ID: 1, Name: "a"}).WherePK().Exec(); !errors.Is(err, errNotExecuted) {
+		t.Fatalf("Exec err = %v", err)
+	}
+	if err := db.Exec(`DELETE FROM test_table`); !errors.Is(err, errNotExecuted) {
+		t.Fatalf("raw Exec err = %v", err)
+	}
+	var list []TestTable
+	if err := db.SELECT(TestTable{}).Many(&list); !errors.Is(err, errNotExecuted) {
+		t.Fatalf("Many err = %v", err)
+	}
+	if got := f.statements(); len(got) != 0 {
+		t.Fatalf("statements = %q", got)
+	}
+}
@@ -1,20 +1,19 @@
 package mworm
 
 import (
+	"context"
 	dbsql "database/sql"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	utilsgo "github.com/ccxdd/utils-go"
 	"github.com/jmoiron/sqlx"
-	"github.com/jmoiron/sqlx/reflectx"
 	jsoniter "github.com/json-iterator/go"
-	"github.com/lib/pq"
-	"github.com/rs/zerolog/log"
 )
 
 // ORMInterface 数据库表结构体接口，需实现 TableName 方法
@@ -34,9 +33,15 @@ const (
 	methodDelete = "DELETE"
 	//
 	emptyUpdateFlag = "eu"
+	nullUpdateFlag  = "nu"
 	emptyInsertFlag = "ei"
 	autoUpdateFlag  = "at"
+	createdFlag     = "created"
+	updatedFlag     = "updated"
+	softDeleteFlag  = "softdelete"
+	versionFlag     = "version"
 	primaryKeyFlag  = "pk"
+	inlineFlag      = "inline"
 )
 
 var (
@@ -52,17 +57,26 @@ type emptyKey = struct{}
 type OrmModel struct {
 	params            map[string]interface{}    // 结构体 Key Value
 	dbFields          map[string]string         // 数据库字段
-	tableName         string                    // 表名
+	tableName         string                    // 表名，已按方言加引号
+	table             string                    // 表名
 	conditionFields   map[string]emptyKey       // 条件字段
 	orderFields       []string                  // 排序字段 column
 	excludeFields     map[string]emptyKey       // 排除字段 json
 	requiredFields    map[string]emptyKey       // 必选字段 json
 	emptyUpdateFields map[string]emptyKey       // 为空时也更新字段 column
-	autoUpdateFields  map[string]emptyKey       // 自动更新字段 column
+	nullUpdateFields  map[string]emptyKey       // 为 nil 时更新为 NULL 的字段 column
+	autoUpdateFields  map[string]emptyKey       // 自动更新时间字段 column
+	createdFields     map[string]emptyKey       // 自动创建时间字段 column
+	softDelete        string                    // 软删除字段 json
+	unscoped          bool                      // 不排除软删除记录，DELETE 时物理删除
+	version           string                    // 乐观锁版本字段 json
+	lockVersion       bool                      // UPDATE 时校验并递增版本
+	entity            any                       // SELECT/INSERT/UPDATE/DELETE 的实体，用于调用钩子
 	method            string                    // SQL 操作方式
 	sql               string                    // SQL 语句
 	err               error                     // 错误提示
-	tagIndexCache     map[string]int            // tag 索引缓存
+	args              []any                     // SQL 绑定参数
+	fieldOrder        []string                  // 结构体字段顺序 json
 	limit             int64                     // SQL LIMIT
 	offset            int64                     // SQL OFFSET
 	log               bool                      // true 时输出 log
@@ -70,64 +84,55 @@ type OrmModel struct {
 	withSQL           string                    // with SQL
 	withOrderFields   []string                  // 子查询排序字段
 	namedCGArr        map[string]ConditionGroup // Where 条件数组
+	namedCGKeys       []string                  // Where 条件顺序
 	namedExec         bool                      // 是否使用了:name变量执行SQL
 	returning         string                    // PQ:专用 RETURNING 语句
 	pk                string                    // primary key column
 	rawSQL            bool                      //
 	distinct          string                    //
-	updateExpressions []string                  // 更新字段 表达式
+	updateExpressions []ConditionGroup          // 更新字段 表达式
 	groupBy           bool                      //
 	groupByRaw        string                    //
+	groupByArgs       []any                     //
 	havingRaw         string                    //
+	havingArgs        []any                     //
 	joinTables        []*JoinTable              // JOIN 表配置
+	bulkRows          []any                     // INSERTMany 的数据行
+	bulkSize          int                       // INSERTMany 每条语句的最大行数
+	upsert            *upsertClause             // UPSERT 冲突处理
+	ctx               context.Context           // 执行 SQL 使用的 context
+	db                *DB                       // 执行 SQL 使用的数据库
+	tx                *Tx                       // 执行 SQL 使用的事务
 }
 
 type SQLParams struct {
 	Sql     string
 	WithSql string
 	Params  map[string]interface{}
+	Args    []any // 与 Sql 中占位符顺序一致的绑定参数
 	Err     error
 }
 
-// BindDB 绑定数据库
+// BindDB 绑定数据库，包级函数使用该连接作为默认实例
 func BindDB(DB *sqlx.DB) error {
 	SqlxDB = DB
+	stdDB = NewDB(DB)
 	return SqlxDB.Ping()
 }
 
 // Table 指定表名
 func Table(name string) *OrmModel {
-	o := &OrmModel{}
-	o.init()
-	if SqlxDB.DriverName() == "postgres" {
-		o.tableName = fmt.Sprintf(`"%s"`, name)
-	} else {
-		o.tableName = name
-	}
-	return o
+	return defaultDB().Table(name)
 }
 
 // BatchArray 批量插入/更新
 func BatchArray(ormArray []*OrmModel) error {
-	tx := SqlxDB.MustBegin()
-	defer func() { _ = tx.Rollback() }()
-	for _, i := range ormArray {
-		o := i
-		if o == nil {
-			continue
-		}
-		result, err := tx.Exec(o.FullSQL().Sql)
-		if err != nil {
-			return err
-		}
-		if _, err = result.RowsAffected(); err != nil {
-			return err
-		}
-	}
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	return nil
+	return defaultDB().BatchArray(ormArray)
+}
+
+// BatchArrayContext 使用 ctx 和事务选项批量插入/更新
+func BatchArrayContext(ctx context.Context, opts *dbsql.TxOptions, ormArray []*OrmModel) error {
+	return defaultDB().BatchArrayContext(ctx, opts, ormArray)
 }
 
 // Batch 批量插入/更新
@@ -135,55 +140,59 @@ func Batch(ormArray ...*OrmModel) error {
 	return BatchArray(ormArray)
 }
 
-// BatchFunc 批量操作
-func BatchFunc(f func(tx *sqlx.Tx)) error {
-	if f == nil {
-		return nil
-	}
-	tx := SqlxDB.MustBegin()
-	defer func() { _ = tx.Rollback() }()
-	f(tx)
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	return nil
+// BatchFunc 在事务中执行 f，f 返回错误时回滚，否则提交
+func BatchFunc(f func(tx *Tx) error) error {
+	return defaultDB().BatchFunc(f)
+}
+
+// BatchFuncContext 使用 ctx 和事务选项在事务中执行 f，f 返回错误时回滚，否则提交
+func BatchFuncContext(ctx context.Context, opts *dbsql.TxOptions, f func(tx *Tx) error) error {
+	return defaultDB().BatchFuncContext(ctx, opts, f)
+}
+
+// Begin 使用默认实例开启事务
+func Begin() (*Tx, error) {
+	return defaultDB().Begin()
+}
+
+// BeginTx 使用默认实例、ctx 和事务选项开启事务
+func BeginTx(ctx context.Context, opts *dbsql.TxOptions) (*Tx, error) {
+	return defaultDB().BeginTx(ctx, opts)
 }
 
 // SELECT 查询
 func SELECT(i ORMInterface, distinct ...bool) *OrmModel {
-	return Table(i.TableName()).setMethod(methodSelect, i, distinct...)
+	return defaultDB().SELECT(i, distinct...)
 }
 
 // INSERT 插入
 func INSERT(i ORMInterface) *OrmModel {
-	return Table(i.TableName()).setMethod(methodInsert, i)
+	return defaultDB().INSERT(i)
 }
 
 // UPDATE 更新
 func UPDATE(i ORMInterface) *OrmModel {
-	return Table(i.TableName()).setMethod(methodUpdate, i)
+	return defaultDB().UPDATE(i)
 }
 
 // DELETE 删除
 func DELETE(i ORMInterface) *OrmModel {
-	return Table(i.TableName()).setMethod(methodDelete, i)
+	return defaultDB().DELETE(i)
 }
 
 // ExecRawSQL 执行原生 SQL
 func ExecRawSQL(sql string, args ...any) error {
-	_, err := SqlxDB.Exec(sql, args...)
-	return err
+	return defaultDB().ExecRawSQL(sql, args...)
 }
 
-// RawSQL 原生 SQL 查询
-func RawSQL(sql string) *OrmModel {
-	o := O()
-	if len(sql) == 0 {
-		o.err = errors.New("invalid sql")
-	}
-	o.rawSQL = true
-	o.sql = sql
-	return o
+// ExecRawSQLContext 使用 ctx 执行原生 SQL
+func ExecRawSQLContext(ctx context.Context, sql string, args ...any) error {
+	return defaultDB().ExecRawSQLContext(ctx, sql, args...)
+}
+
+// RawSQL 原生 SQL 查询，args 按驱动占位符顺序绑定
+func RawSQL(sql string, args ...any) *OrmModel {
+	return defaultDB().RawSQL(sql, args...)
 }
 
 func (o *OrmModel) init() {
@@ -191,7 +200,9 @@ func (o *OrmModel) init() {
 	o.excludeFields = make(map[string]emptyKey)
 	o.conditionFields = make(map[string]emptyKey)
 	o.emptyUpdateFields = make(map[string]emptyKey)
+	o.nullUpdateFields = make(map[string]emptyKey)
 	o.autoUpdateFields = make(map[string]emptyKey)
+	o.createdFields = make(map[string]emptyKey)
 	o.namedCGArr = make(map[string]ConditionGroup)
 }
 
@@ -214,18 +225,16 @@ func (o *OrmModel) Delete(i interface{}) *OrmModel {
 func (o *OrmModel) setMethod(method string, i interface{}, distinct ...bool) *OrmModel {
 	o.structToMap(i)
 	o.method = method
+	o.entity = i
 	if o.method == methodSelect && len(distinct) > 0 && distinct[0] {
 		o.distinct = "DISTINCT"
 	}
-	//if crud, b := i.(CRUDInterface); b {
-	//	crud.CRUDMode(method)
-	//}
 	return o
 }
 
 func (o *OrmModel) Desc(jsonTag ...string) *OrmModel {
 	for _, f := range jsonTag {
-		dbField := o.dbFields[f]
+		dbField := o.columnField(f)
 		if len(dbField) > 0 {
 			o.orderFields = append(o.orderFields, dbField+` DESC`)
 		}
@@ -235,7 +244,7 @@ func (o *OrmModel) Desc(jsonTag ...string) *OrmModel {
 
 func (o *OrmModel) Asc(jsonTag ...string) *OrmModel {
 	for _, f := range jsonTag {
-		dbField := o.dbFields[f]
+		dbField := o.columnField(f)
 		if len(dbField) > 0 {
 			o.orderFields = append(o.orderFields, dbField)
 		}
@@ -253,6 +262,17 @@ func (o *OrmModel) AllowEmpty(jsonTag ...string) *OrmModel {
 	return o
 }
 
+// AllowNull UPDATE 时字段为 nil 指针(或 Valid 为 false 的 sql.NullXXX)则更新为 NULL，等同 db tag 的 nu 标记
+func (o *OrmModel) AllowNull(jsonTag ...string) *OrmModel {
+	for _, j := range jsonTag {
+		dbField := o.dbFields[j]
+		if len(dbField) > 0 {
+			o.nullUpdateFields[dbField] = emptyKey{}
+		}
+	}
+	return o
+}
+
 func (o *OrmModel) ExcludeFields(jsonTag ...string) *OrmModel {
 	for _, j := range jsonTag {
 		o.excludeFields[j] = emptyKey{}
@@ -291,6 +311,33 @@ func (o *OrmModel) Log(l bool) *OrmModel {
 	return o
 }
 
+// Ctx 设置执行 SQL 使用的 context，用于传递取消信号和超时
+func (o *OrmModel) Ctx(ctx context.Context) *OrmModel {
+	o.ctx = ctx
+	return o
+}
+
+// database 返回执行 SQL 使用的数据库，未指定时为默认实例
+func (o *OrmModel) database() *DB {
+	if o.db == nil {
+		return defaultDB()
+	}
+	return o.db
+}
+
+// dialect 返回数据库方言
+func (o *OrmModel) dialect() Dialect {
+	return o.database().Dialect()
+}
+
+// context 返回执行 SQL 使用的 context，未设置时为 context.Background()
+func (o *OrmModel) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
 func (o *OrmModel) WithAsc(fields ...string) *OrmModel {
 	for _, f := range fields {
 		if len(f) > 0 {
@@ -318,33 +365,35 @@ func (o *OrmModel) whereSQL() string {
 //
 // 该函数不接受任何参数。
 // 它返回一个错误。
-func (o *OrmModel) Exec() error {
-	var count int64
-	if o.rawSQL {
-		if len(o.params) > 0 {
-			o.err = Exec(o.sql)
-		} else {
-			count, o.err = SqlxDB.MustExec(o.sql).RowsAffected()
-			if count == 0 && o.err == nil {
-				o.err = errors.New(`影响行数为0`)
-			}
-		}
-	} else {
-		o.err = Exec(o.FullSQL().Sql)
+func (o *OrmModel) Exec() (err error) {
+	end := o.startSpan("Exec")
+	defer func() { end(err) }()
+	if o.upsert != nil || o.bulkRows != nil {
+		// 冲突时忽略或更新后值未变（MySQL），影响行数可能为 0；INSERTMany 没有数据行时不执行
+		_, o.err = o.execResult()
+		return o.err
 	}
+	o.err = checkAffected(o.execResult())
 	return o.err
 }
 
 // Count 统计数量
-func (o *OrmModel) Count(column string) (int64, error) {
+func (o *OrmModel) Count(column string) (n int64, err error) {
+	end := o.startSpan("Count")
+	defer func() { end(err) }()
 	var result int64
-	o.sql = fmt.Sprintf(`SELECT count(%s) %s %s %s`, column, `FROM`, o.tableName, o.whereSQL())
-	if o.log || DebugMode {
-		log.Debug().Str("sql", o.sql)
-		fmt.Println("sql:", o.sql)
+	o.args = nil
+	o.sql = fmt.Sprintf(`SELECT count(%s) %s %s %s`, column, `FROM`, o.fromSQL(), o.whereSQL())
+	if o.err != nil {
+		return 0, o.err
+	}
+	db := o.executor()
+	if db == nil {
+		o.err = ErrNilDB
+		return 0, o.err
 	}
 	var rows *sqlx.Rows
-	rows, o.err = SqlxDB.Queryx(o.sql)
+	rows, o.err = db.QueryxContext(o.context(), o.sql, o.args...)
 	if o.err != nil {
 		return 0, o.err
 	}
@@ -357,115 +406,133 @@ func (o *OrmModel) Count(column string) (int64, error) {
 
 // One 查询单条记录
 func (o *OrmModel) One(dest interface{}) error {
-	if SqlxDB == nil {
-		o.err = errors.New(`SqlxDB *sqlx.DB is nil`)
-		return o.err
+	_, err := o.one(dest)
+	return err
+}
+
+// one 查询单条记录，found 表示是否查询到记录
+func (o *OrmModel) one(dest interface{}) (found bool, err error) {
+	end := o.startSpan("One")
+	defer func() { end(err) }()
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr {
+		o.err = errors.New(`error: t.Kind() != reflect.Prt`)
+		return false, o.err
 	}
-	fieldMap := make(map[string]interface{})
-	var rows *sqlx.Rows
-	if o.rawSQL {
-		if len(o.params) > 0 && o.namedExec {
-			rows, o.err = SqlxDB.NamedQuery(o.sql, o.params)
-		} else {
-			rows, o.err = SqlxDB.Queryx(o.sql)
-		}
-	} else {
-		rows, o.err = SqlxDB.Queryx(o.Limit(1).FullSQL().Sql)
+	if !o.rawSQL {
+		o.Limit(1)
 	}
-	if o.err != nil {
-		return o.err
+	rows, err := o.queryRows()
+	if err != nil {
+		return false, err
 	}
 	defer func() { _ = rows.Close() }()
-	if rows.Next() {
-		if o.err = rows.MapScan(fieldMap); o.err != nil {
-			return o.err
+	if !rows.Next() {
+		o.err = rows.Err()
+		return false, o.err
+	}
+	rowType, rowValue := t.Elem(), reflect.Indirect(reflect.ValueOf(dest))
+	// dest 为结构体指针的指针时分配结构体
+	if rowType.Kind() == reflect.Ptr && rowType.Elem().Kind() == reflect.Struct && !isValueType(rowType.Elem()) {
+		rowType = rowType.Elem()
+		if rowValue.IsNil() {
+			rowValue.Set(reflect.New(rowType))
 		}
+		rowValue = rowValue.Elem()
 	}
-
-	t := reflect.TypeOf(dest)
-	if t.Kind() != reflect.Ptr {
-		o.err = errors.New(`error: t.Kind() != reflect.Prt`)
-	} else {
-		t = t.Elem()
+	sc, err := newRowScanner(o.context(), rows, rowType)
+	if err != nil {
+		o.err = err
+		return false, err
 	}
-	v := reflect.ValueOf(dest)
-	v = reflect.Indirect(v)
-	_ = o.bindRow(t, v, fieldMap)
-	return o.err
+	o.err = sc.scan(rows, rowValue)
+	return true, o.err
 }
 
 // Many 查询多条记录
-func (o *OrmModel) Many(dest interface{}) error {
-	if SqlxDB == nil {
-		o.err = errors.New(`SqlxDB *sqlx.DB is nil`)
-		return o.err
-	}
+func (o *OrmModel) Many(dest interface{}) (err error) {
+	end := o.startSpan("Many")
+	defer func() { end(err) }()
 	if (o.method != methodSelect && len(o.returning) == 0) && !o.rawSQL {
 		o.err = errors.New(`o.method must be [methodSelect]`)
 		return o.err
 	}
 	// 目标类型
 	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Ptr {
+		o.err = errors.New(`error: Kind() != reflect.Prt`)
+		return o.err
+	}
 	if destValue.IsNil() {
 		return errors.New("nil pointer passed to StructScan destination")
 	}
-	if destValue.Kind() != reflect.Ptr {
-		o.err = errors.New(`error: Kind() != reflect.Prt`)
+	if destValue.Elem().Kind() != reflect.Slice {
+		o.err = errors.New(`error: Kind() != reflect.Slice`)
 		return o.err
-	} else {
-		if destValue.Elem().Kind() != reflect.Slice {
-			o.err = errors.New(`error: Kind() != reflect.Slice`)
-			return o.err
-		}
 	}
 
-	var rowType reflect.Type
-	var isPtr bool
 	// 获取目标地址中的类型值
 	destValue = reflect.Indirect(destValue)
-	// 类型值对应的类型
-	rowType = reflectx.Deref(destValue.Type())
-	// 类型是否数组
-	if rowType.Kind() == reflect.Slice {
-		// 数组子类型
+	// 数组子类型
+	rowType := destValue.Type().Elem()
+	// 子类型是否指针
+	isPtr := rowType.Kind() == reflect.Ptr
+	if isPtr {
 		rowType = rowType.Elem()
-		isPtr = rowType.Kind() == reflect.Ptr
-		// 子类型是否指针
-		if isPtr {
-			rowType = rowType.Elem()
-		}
-	}
-	// rows
-	var rows *sqlx.Rows
-	if o.rawSQL {
-		if len(o.params) > 0 && o.namedExec {
-			rows, o.err = SqlxDB.NamedQuery(o.sql, o.params)
-		} else {
-			rows, o.err = SqlxDB.Queryx(o.sql)
-		}
-	} else {
-		rows, o.err = SqlxDB.Queryx(o.FullSQL().Sql)
 	}
-	if o.err != nil {
-		return o.err
+	rows, err := o.queryRows()
+	if err != nil {
+		return err
 	}
 	defer func() { _ = rows.Close() }()
-	var rowValue, rowValuePtr reflect.Value
+	sc, err := newRowScanner(o.context(), rows, rowType)
+	if err != nil {
+		o.err = err
+		return err
+	}
 	for rows.Next() {
-		fieldMap := make(map[string]interface{})
-		o.err = rows.MapScan(fieldMap)
-		rowValuePtr = reflect.New(rowType)
-		rowValue = reflect.Indirect(rowValuePtr)
-		_ = o.bindRow(rowType, rowValue, fieldMap)
+		rowValuePtr := reflect.New(rowType)
+		rowValue := reflect.Indirect(rowValuePtr)
+		if o.err = sc.scan(rows, rowValue); o.err != nil {
+			return o.err
+		}
 		if isPtr {
 			destValue.Set(reflect.Append(destValue, rowValuePtr))
 		} else {
 			destValue.Set(reflect.Append(destValue, rowValue))
 		}
 	}
+	o.err = rows.Err()
 	return o.err
 }
 
+// queryRows 执行查询并返回 *sqlx.Rows，调用方负责关闭
+func (o *OrmModel) queryRows() (*sqlx.Rows, error) {
+	db := o.executor()
+	if db == nil {
+		o.err = ErrNilDB
+		return nil, o.err
+	}
+	var rows *sqlx.Rows
+	if o.rawSQL {
+		if o.err != nil {
+			return nil, o.err
+		}
+		if len(o.params) > 0 && o.namedExec {
+			rows, o.err = sqlx.NamedQueryContext(o.context(), db, o.sql, o.params)
+		} else {
+			rows, o.err = db.QueryxContext(o.context(), o.sql, o.args...)
+		}
+	} else {
+		sqlParams := o.FullSQL()
+		if sqlParams.Err != nil {
+			return nil, sqlParams.Err
+		}
+		rows, o.err = db.QueryxContext(o.context(), sqlParams.Sql, sqlParams.Args...)
+	}
+	return rows, o.err
+}
+
 // With 关联查询
 func (o *OrmModel) With(t string) *OrmModel {
 	if o.method != methodSelect {
@@ -488,16 +555,8 @@ func (o *OrmModel) GroupBy(cgs ...ConditionGroup) *OrmModel {
 				o.requiredFields[f] = emptyKey{}
 			}
 		case cgTypeRaw:
-			if len(c.Args) == 0 {
-				o.groupByRaw = c.Express
-			} else {
-				tmp := c.Express
-				for i, arg := range c.Args {
-					vStr := ValueTypeToStr(arg)
-					tmp = strings.Replace(tmp, "$"+strconv.Itoa(i+1), vStr, 1)
-				}
-				o.groupByRaw = tmp
-			}
+			o.groupByRaw = c.Express
+			o.groupByArgs = c.Args
 		default:
 			panic("unhandled default case")
 		}
@@ -509,16 +568,8 @@ func (o *OrmModel) Having(exp string, args ...any) *OrmModel {
 	if exp == "" {
 		return o
 	}
-	if len(args) == 0 {
-		o.groupByRaw = exp
-	} else {
-		tmp := exp
-		for i, arg := range args {
-			vStr := ValueTypeToStr(arg)
-			tmp = strings.Replace(tmp, "$"+strconv.Itoa(i+1), vStr, 1)
-		}
-		o.havingRaw = tmp
-	}
+	o.havingRaw = exp
+	o.havingArgs = args
 	return o
 }
 
@@ -526,44 +577,14 @@ func (o *OrmModel) JsonbMapString(keys ...string) (string, error) {
 	if len(keys) == 0 {
 		return "", nil
 	}
-	var orderBy string
+	d := o.dialect()
 	for i, key := range keys {
 		if key == "row" {
-			keys[i] = fmt.Sprintf(`jsonb_build_object(%s)`, dbMapBuildObjString(o.dbFields))
+			keys[i] = d.JSONBuildObject(dbMapBuildObjString(o.dbFields))
 		}
 	}
-	keysStr := strings.Join(keys, ",")
 	sqlParams := o.BuildSQL()
-	if len(o.withSQL) > 0 {
-		if len(o.withOrderFields) > 0 {
-			orderBy = fmt.Sprintf(`ORDER BY %s`, strings.Join(o.withOrderFields, ","))
-			subSql := fmt.Sprintf(`SELECT * %s %s %s`, `FROM`, o.withTable, orderBy)
-			o.sql = fmt.Sprintf(`%s SELECT jsonb_object_agg(%s) FROM (%s) row`, o.withSQL, keysStr, subSql)
-		} else {
-			o.sql = fmt.Sprintf(`%s SELECT jsonb_object_agg(%s) FROM %s row`, o.withSQL, keysStr, o.withTable)
-		}
-	} else {
-		o.sql = fmt.Sprintf(`%s(%s) FROM (%s) row`, `SELECT jsonb_object_agg`, keysStr, sqlParams.Sql)
-	}
-	var result string
-	if o.log || DebugMode {
-		log.Debug().Str("sql", o.sql)
-		fmt.Println("sql:", o.sql)
-	}
-	var rows *sqlx.Rows
-	rows, o.err = SqlxDB.Queryx(o.sql)
-	if o.err != nil {
-		return "", o.err
-	}
-	defer func() { _ = rows.Close() }()
-	if rows.Next() {
-		m := map[string]interface{}{}
-		o.err = rows.MapScan(m)
-		if m["jsonb_object_agg"] != nil {
-			result = string(m["jsonb_object_agg"].([]uint8))
-		}
-	}
-	return result, o.err
+	return o.queryJSON(d.JSONObjectAgg(strings.Join(keys, ",")), sqlParams)
 }
 
 func (o *OrmModel) JsonbMap(dest interface{}, columns ...string) error {
@@ -573,106 +594,65 @@ func (o *OrmModel) JsonbMap(dest interface{}, columns ...string) error {
 	}
 	return err
 }
+
 func (o *OrmModel) JsonbListString() (string, error) {
-	var orderBy string
+	d := o.dialect()
 	sqlParams := o.BuildSQL()
-	rowKeys := fmt.Sprintf(`jsonb_build_object(%s)`, dbMapBuildObjString(o.dbFields))
+	rowKeys := d.JSONBuildObject(dbMapBuildObjString(o.dbFields))
+	return o.queryJSON(d.JSONAgg(rowKeys), sqlParams)
+}
+
+func (o *OrmModel) JsonbList(dest interface{}) error {
+	var jsonStr, err = o.JsonbListString()
+	if len(jsonStr) > 0 {
+		return jsoniter.UnmarshalFromString(jsonStr, dest)
+	}
+	return err
+}
+
+// queryJSON 使用聚合表达式 agg 将查询结果聚合为 JSON 字符串
+func (o *OrmModel) queryJSON(agg string, sqlParams SQLParams) (string, error) {
+	if sqlParams.Err != nil {
+		return "", sqlParams.Err
+	}
+	alias := o.dialect().Quote(`row`)
 	if len(o.withSQL) > 0 {
 		if len(o.withOrderFields) > 0 {
-			orderBy = fmt.Sprintf(`ORDER BY %s`, strings.Join(o.withOrderFields, ","))
+			orderBy := fmt.Sprintf(`ORDER BY %s`, strings.Join(o.withOrderFields, ","))
 			subSql := fmt.Sprintf(`SELECT * %s %s %s`, `FROM`, o.withTable, orderBy)
-			o.sql = fmt.Sprintf(`%s SELECT jsonb_agg(%s) FROM (%s) row`, rowKeys, o.withSQL, subSql)
+			o.sql = fmt.Sprintf(`%s SELECT %s FROM (%s) %s`, o.withSQL, agg, subSql, alias)
 		} else {
-			o.sql = fmt.Sprintf(`%s SELECT jsonb_agg(%s) FROM %s row`, rowKeys, o.withSQL, o.withTable)
+			o.sql = fmt.Sprintf(`%s SELECT %s FROM %s %s`, o.withSQL, agg, o.withTable, alias)
 		}
 	} else {
-		o.sql = fmt.Sprintf(`SELECT jsonb_agg(%s) %s (%s) row`, rowKeys, `FROM`, sqlParams.Sql)
+		o.sql = fmt.Sprintf(`SELECT %s FROM (%s) %s`, agg, sqlParams.Sql, alias)
 	}
-	var result string
-	if o.log || DebugMode {
-		log.Debug().Str("sql", o.sql)
-		fmt.Println("sql:", o.sql)
+	db := o.executor()
+	if db == nil {
+		o.err = ErrNilDB
+		return "", o.err
 	}
 	var rows *sqlx.Rows
-	rows, o.err = SqlxDB.Queryx(o.sql)
+	rows, o.err = db.QueryxContext(o.context(), o.sql, sqlParams.Args...)
 	if o.err != nil {
 		return "", o.err
 	}
 	defer func() { _ = rows.Close() }()
+	var result dbsql.NullString
 	if rows.Next() {
-		m := map[string]interface{}{}
-		o.err = rows.MapScan(m)
-		if m["jsonb_agg"] != nil {
-			result = string(m["jsonb_agg"].([]uint8))
-		}
-	}
-	return result, o.err
-}
-
-func (o *OrmModel) JsonbList(dest interface{}) error {
-	var jsonStr, err = o.JsonbListString()
-	if len(jsonStr) > 0 {
-		return jsoniter.UnmarshalFromString(jsonStr, dest)
+		o.err = rows.Scan(&result)
 	}
-	return err
+	return result.String, o.err
 }
 
-func (o *OrmModel) bindRow(t reflect.Type, v reflect.Value, values map[string]interface{}) error {
-	if t.Kind() != reflect.Struct {
-		for _, i := range values {
-			if o.err = setStructValue(v, i); o.err != nil {
-				return o.err
-			}
-		}
-		return nil
-	}
-	if len(o.tagIndexCache) == 0 {
-		o.tagIndexCache = make(map[string]int)
-		for i := 0; i < t.NumField(); i++ {
-			dbTag := t.Field(i).Tag.Get(TagName)
-			if len(dbTag) == 0 {
-				continue
-			}
-			arr := strings.Split(dbTag, ",")
-			dbTag = strings.TrimSpace(arr[0])
-			o.tagIndexCache[dbTag] = i
-		}
-	}
-	for tag, i := range o.tagIndexCache {
-		destField := v.Field(i)
-		if o.err = setStructValue(destField, values[tag]); o.err != nil {
-			return o.err
-		}
-	}
-	return nil
+// Exec 执行 SQL 语句，args 按驱动占位符顺序绑定
+func Exec(sqlStr string, args ...any) error {
+	return defaultDB().Exec(sqlStr, args...)
 }
 
-// Exec 执行带命名参数的 SQL 语句
-func Exec(sqlStr string) error {
-	var err error
-	var result dbsql.Result
-	f := func() {
-		var count int64
-		if SqlxDB == nil {
-			err = errors.New(`SqlxDB *sqlx.DB is nil`)
-		}
-		defer func() {
-			if e := recover(); e != nil {
-				err = errors.New(e.(*pq.Error).Message)
-				log.Error().Msg(e.(*pq.Error).Message)
-			}
-		}()
-		result, err = SqlxDB.Exec(sqlStr)
-		if err != nil {
-			return
-		}
-		count, err = result.RowsAffected()
-		if count == 0 && err == nil {
-			err = errors.New(`影响行数为0`)
-		}
-	}
-	f()
-	return err
+// ExecContext 使用 ctx 执行 SQL 语句，args 按驱动占位符顺序绑定
+func ExecContext(ctx context.Context, sqlStr string, args ...any) error {
+	return defaultDB().ExecContext(ctx, sqlStr, args...)
 }
 
 func valToString(v interface{}, format string) string {
@@ -720,13 +700,35 @@ func (o *OrmModel) columnField(json string) string {
 	if column, ok := o.dbFields[json]; ok {
 		return column
 	}
+	// JOIN 查询时 alias.jsonTag 指定表的字段
+	if alias, tag, ok := strings.Cut(json, "."); ok && len(o.joinTables) > 0 {
+		if alias == joinMainAlias {
+			if column := o.dbFields[tag]; len(column) > 0 {
+				return alias + "." + column
+			}
+		}
+		for _, join := range o.joinTables {
+			if join.Alias != alias {
+				continue
+			}
+			if column := join.dbFields[tag]; len(column) > 0 {
+				return alias + "." + column
+			}
+		}
+	}
 	return ""
 }
 
 func setStructValue(rv reflect.Value, val interface{}) error {
 	if val == nil {
+		if rv.Kind() == reflect.Ptr {
+			rv.SetZero()
+		}
 		return nil
 	}
+	if isTimeType(rv.Type()) {
+		return setTimeValue(rv, val)
+	}
 	kind := rv.Kind()
 	fieldType := rv.Type().String()
 	switch kind {
@@ -751,69 +753,113 @@ func setStructValue(rv reflect.Value, val interface{}) error {
 		a := utilsgo.StringToFloat(s)
 		rv.SetFloat(a)
 	case reflect.Ptr:
-		if val == nil {
-			break
-		}
-		switch fieldType {
-		case "*string":
-			a := val.(string)
-			rv.Set(reflect.ValueOf(&a))
-		default:
-			switch val.(type) {
-			case []byte:
-				r := rv.Addr().Interface()
-				if err := jsoniter.Unmarshal(val.([]byte), r); err != nil {
-					return err
-				}
-			default:
-				return fmt.Errorf("error: (%s) type not processed, because value: %v", fieldType, val)
-			}
+		elem := reflect.New(rv.Type().Elem())
+		if err := setStructValue(elem.Elem(), val); err != nil {
+			return err
 		}
+		rv.Set(elem)
 	default:
 		switch typeValue := val.(type) {
 		case string:
-			rv.SetString(typeValue)
+			switch kind {
+			case reflect.Slice, reflect.Map, reflect.Struct: // SQLite 的 JSON 以文本返回
+				return jsoniter.UnmarshalFromString(typeValue, rv.Addr().Interface())
+			case reflect.String:
+				rv.SetString(typeValue)
+			case reflect.Bool, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return setTextValue(rv, typeValue)
+			default:
+				return typeMismatch(fieldType, val)
+			}
 		case int64:
-			rv.SetInt(typeValue)
+			switch kind {
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				if typeValue < 0 || rv.OverflowUint(uint64(typeValue)) {
+					return typeMismatch(fieldType, val)
+				}
+				rv.SetUint(uint64(typeValue))
+			case reflect.Bool:
+				rv.SetBool(typeValue != 0)
+			default:
+				return typeMismatch(fieldType, val)
+			}
 		case uint64:
-			rv.SetUint(typeValue)
-		case float64:
-			rv.SetFloat(typeValue)
+			switch kind {
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				rv.SetUint(typeValue)
+			default:
+				return typeMismatch(fieldType, val)
+			}
 		case bool:
+			if kind != reflect.Bool {
+				return typeMismatch(fieldType, val)
+			}
 			rv.SetBool(typeValue)
 		case []byte: // PQ Field: jsonb, numeric
+			switch kind {
+			case reflect.Bool, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				// MySQL 文本协议以 []byte 返回 TINYINT(1) 与无符号整数
+				return setTextValue(rv, string(typeValue))
+			}
 			switch fieldType {
-			case "float64":
-				a := string(typeValue)
-				rv.SetFloat(utilsgo.StringToFloat(a))
 			case "string":
 				a := string(typeValue)
 				rv.SetString(a)
-			case "int64":
-				a := string(typeValue)
-				rv.SetInt(utilsgo.FloatToInt(utilsgo.StringToFloat(a)))
 			default:
+				if !rv.CanAddr() {
+					return typeMismatch(fieldType, val)
+				}
 				r := rv.Addr().Interface()
 				if err := jsoniter.Unmarshal(typeValue, r); err != nil {
 					return err
 				}
 			}
 		case time.Time:
-			t := typeValue.Format(utilsgo.YYYYMMDDHHMMSS)
+			if kind != reflect.String {
+				return typeMismatch(fieldType, val)
+			}
+			t := localTime(typeValue).Format(utilsgo.YYYYMMDDHHMMSS)
 			rv.SetString(t)
 		default:
-			return fmt.Errorf("error: (%s) type not processed, because value: %v", fieldType, val)
+			return typeMismatch(fieldType, val)
 		}
 	}
 	return nil
 }
 
+// setTextValue 将文本形式的列值解析为 bool 或无符号整数字段，"0"/"1" 可解析为 bool
+func setTextValue(rv reflect.Value, s string) error {
+	if rv.Kind() == reflect.Bool {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return typeMismatch(rv.Type().String(), s)
+		}
+		rv.SetBool(b)
+		return nil
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return typeMismatch(rv.Type().String(), s)
+	}
+	if rv.OverflowUint(u) {
+		return fmt.Errorf("error: value %s overflows %s", s, rv.Type())
+	}
+	rv.SetUint(u)
+	return nil
+}
+
+// typeMismatch 列值无法转换为字段类型时返回的错误
+func typeMismatch(fieldType string, val any) error {
+	return fmt.Errorf("error: (%s) type not processed, because value: %v", fieldType, val)
+}
+
 func (o *OrmModel) structToMap(item any) (map[string]any, map[string]string) {
 	jsonKeys := map[string]any{}
 	columnFields := map[string]string{}
 	if item == nil {
 		return jsonKeys, columnFields
 	}
+	o.fieldOrder = nil
 	t := reflect.TypeOf(item)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
@@ -823,36 +869,67 @@ func (o *OrmModel) structToMap(item any) (map[string]any, map[string]string) {
 	}
 	reflectValue := reflect.ValueOf(item)
 	reflectValue = reflect.Indirect(reflectValue)
+	o.walkStruct(reflectValue, "", "", false, jsonKeys, columnFields)
+	o.params, o.dbFields = jsonKeys, columnFields
+	return jsonKeys, columnFields
+}
+
+// walkStruct 将结构体 v 的字段写入 jsonKeys 与 columnFields，嵌入结构体直接展开，inline 结构体加前缀展开；
+// promoted 为 true 时不覆盖外层已有的字段
+func (o *OrmModel) walkStruct(v reflect.Value, jsonPrefix, columnPrefix string, promoted bool, jsonKeys map[string]any,
+	columnFields map[string]string) {
+	t := v.Type()
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		jsonTag := field.Tag.Get("json")
 		jsonName := strings.TrimSpace(strings.Split(jsonTag, ",")[0])
-		if !reflectValue.Field(i).CanInterface() {
+		if !field.IsExported() && (!field.Anonymous || field.Type.Kind() == reflect.Ptr) {
+			continue
+		}
+		if prefix, inline, ok := nestedPrefix(field); ok {
+			sv := v.Field(i)
+			if sv.Kind() == reflect.Ptr {
+				if sv.IsNil() {
+					sv = reflect.Zero(sv.Type().Elem())
+				} else {
+					sv = sv.Elem()
+				}
+			}
+			if inline {
+				subPrefix := jsonPrefix
+				if len(jsonName) > 0 {
+					subPrefix += jsonName + "."
+				}
+				o.walkStruct(sv, subPrefix, columnPrefix+prefix, promoted, jsonKeys, columnFields)
+			} else {
+				o.walkStruct(sv, jsonPrefix, columnPrefix, true, jsonKeys, columnFields)
+			}
 			continue
 		}
-		fieldValue := reflectValue.Field(i).Interface()
+		if len(jsonName) > 0 {
+			jsonName = jsonPrefix + jsonName
+		}
+		if _, ok := columnFields[jsonName]; (ok && promoted) || !field.IsExported() {
+			continue
+		}
+		fieldValue := v.Field(i).Interface()
+		// 实现 driver.Valuer 的结构体作为字段值，不展开
+		isStruct := field.Type.Kind() == reflect.Struct && !isValueType(field.Type)
 		if jsonTag != "" && jsonTag != "-" {
-			if field.Type.Kind() == reflect.Struct {
+			if isStruct {
 				jsonKeys[jsonName], _ = StructToMap(fieldValue)
 			} else {
 				jsonKeys[jsonName] = fieldValue
 			}
-		} else if field.Type.Kind() == reflect.Struct {
-			subMap, subDbMap := StructToMap(fieldValue)
-			for kk, vv := range subMap {
-				jsonKeys[kk] = vv
-			}
-			for kk, vv := range subDbMap {
-				columnFields[kk] = vv
-			}
 		}
 		// db Tag
-		dbTag := t.Field(i).Tag.Get(TagName)
+		dbTag := field.Tag.Get(TagName)
 		if dbTag != "" && dbTag != "-" {
 			dbTagArr := strings.Split(dbTag, ",")
-			dbColumnName := strings.TrimSpace(dbTagArr[0])
+			dbColumnName := columnPrefix + strings.TrimSpace(dbTagArr[0])
 			if len(dbTagArr) > 0 {
 				columnFields[jsonName] = dbColumnName
+				o.fieldOrder = append(o.fieldOrder, jsonName)
 			}
 			// db Flag
 			for _, flag := range dbTagArr[1:] {
@@ -862,8 +939,16 @@ func (o *OrmModel) structToMap(item any) (map[string]any, map[string]string) {
 				case emptyInsertFlag:
 				case emptyUpdateFlag:
 					o.emptyUpdateFields[dbColumnName] = emptyKey{}
-				case autoUpdateFlag:
+				case nullUpdateFlag:
+					o.nullUpdateFields[dbColumnName] = emptyKey{}
+				case autoUpdateFlag, updatedFlag:
 					o.autoUpdateFields[dbColumnName] = emptyKey{}
+				case createdFlag:
+					o.createdFields[dbColumnName] = emptyKey{}
+				case softDeleteFlag:
+					o.softDelete = jsonName
+				case versionFlag:
+					o.version = jsonName
 				}
 			}
 			if jsonName != dbColumnName {
@@ -871,15 +956,65 @@ func (o *OrmModel) structToMap(item any) (map[string]any, map[string]string) {
 			}
 		}
 	}
-	o.params, o.dbFields = jsonKeys, columnFields
-	return jsonKeys, columnFields
+}
+
+// nestedPrefix 字段是否展开到所在结构体：db tag 带 inline 标记的结构体以 tag 名为列前缀展开，
+// 没有 json tag 与列名的嵌入结构体(指针)直接展开
+func nestedPrefix(field reflect.StructField) (prefix string, inline, ok bool) {
+	dbTagArr := strings.Split(field.Tag.Get(TagName), ",")
+	name := strings.TrimSpace(dbTagArr[0])
+	for _, flag := range dbTagArr[1:] {
+		if flag == inlineFlag {
+			inline = true
+		}
+	}
+	t := field.Type
+	if t.Kind() == reflect.Ptr && (field.Anonymous || inline) {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isValueType(t) {
+		return "", false, false
+	}
+	if inline {
+		return name, true, true
+	}
+	jsonTag := field.Tag.Get("json")
+	if (jsonTag != "" && jsonTag != "-") || (len(name) > 0 && name != "-") {
+		return "", false, false
+	}
+	return "", false, true
 }
 
 func StructToMap(item any) (map[string]any, map[string]string) {
 	orm := new(OrmModel)
+	orm.init()
 	return orm.structToMap(item)
 }
 
+// orderedKeys 按结构体字段顺序 order 返回 m 中的 key，保证生成的 SQL 与参数顺序稳定
+func orderedKeys[V any](order []string, m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	seen := make(map[string]emptyKey, len(m))
+	for _, k := range order {
+		if _, ok := m[k]; !ok {
+			continue
+		}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = emptyKey{}
+		keys = append(keys, k)
+	}
+	rest := make([]string, 0)
+	for k := range m {
+		if _, ok := seen[k]; !ok {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
 func (o *OrmModel) Error() error {
 	if o == nil {
 		return nil
@@ -1,6 +1,8 @@
 package mworm
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
@@ -123,6 +125,32 @@ func TestInsertUpdate(t *testing.T) {
 	}
 }
 
+func TestContextCanceled(t *testing.T) {
+	db, f := newFakeDB(t)
+	old := SqlxDB
+	SqlxDB = db.Sqlx()
+	t.Cleanup(func() { SqlxDB = old })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var list []TestTable
+	if err := SELECT(TestTable{}).Ctx(ctx).Many(&list); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Many err = %v", err)
+	}
+	if _, err := SELECT(TestTable{}).Ctx(ctx).Count("*"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Count err = %v", err)
+	}
+	if err := UPDATE(TestTable{ID: 1, Name: "a"}).WherePK().Ctx(ctx).Exec(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Exec err = %v", err)
+	}
+	if err := BatchFuncContext(ctx, nil, func(tx *Tx) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("BatchFuncContext err = %v", err)
+	}
+	if got := f.statements(); len(got) != 0 {
+		t.Fatalf("statements = %q", got)
+	}
+}
+
 func TestStructMap(t *testing.T) {
 	//
 	test := TestStruct{}
@@ -1,17 +1,17 @@
 package mworm
 
 import (
+	"context"
 	"crypto/md5"
-	dbsql "database/sql"
+	"database/sql/driver"
 	"encoding/hex"
 	"fmt"
 	"reflect"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	jsoniter "github.com/json-iterator/go"
-	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
@@ -23,77 +23,73 @@ func (o *OrmModel) Where(cgs ...ConditionGroup) *OrmModel {
 	for _, cg := range cgs {
 		digest := md5.Sum([]byte(strings.Join(cg.JsonTags, "") + cg.Express + cg.Logic + cg.Symbol +
 			fmt.Sprintf(`%v`, cg.cType)))
-		o.namedCGArr[hex.EncodeToString(digest[:])] = cg
+		o.addCondition(hex.EncodeToString(digest[:]), cg)
 	}
 	return o
 }
 
-// BuildSQL 构造带命名参数的 SQL 语句
-func (o *OrmModel) BuildSQL() SQLParams {
-	newParams := make(map[string]interface{})
-	fieldValueMap := make(map[string]interface{})
-	for s, i := range o.params {
-		newParams[s] = i
+// addCondition 按 key 去重并保持条件的添加顺序
+func (o *OrmModel) addCondition(key string, cg ConditionGroup) {
+	if _, ok := o.namedCGArr[key]; !ok {
+		o.namedCGKeys = append(o.namedCGKeys, key)
 	}
-	var conditionSQL = o.parseConditionNamed()
+	o.namedCGArr[key] = cg
+}
+
+// BuildSQL 构造带绑定参数的 SQL 语句，INSERTMany 时返回第一条语句
+func (o *OrmModel) BuildSQL() SQLParams {
+	o.args = nil
 	if o.err != nil {
 		return SQLParams{Err: o.err}
 	}
-	// 排除不参与拼接的 Key
-	if len(o.excludeFields) > 0 {
-		for k := range o.excludeFields {
-			delete(newParams, k)
+	if o.bulkRows != nil {
+		sqls, err := o.BuildBulkSQL()
+		if err != nil {
+			return SQLParams{Err: err}
 		}
-	}
-	// 保留字段
-	if len(o.requiredFields) > 0 {
-		for k := range o.requiredFields {
-			if v, ok := newParams[k]; ok {
-				fieldValueMap[k] = v
-			}
+		if len(sqls) == 0 {
+			return SQLParams{Err: ErrEmptySQL}
 		}
-		newParams = fieldValueMap
-	} else if o.method == methodUpdate && len(o.updateExpressions) > 0 {
-		newParams = make(map[string]interface{})
+		return sqls[0]
 	}
+	newParams := o.filterParams(o.params)
 	// 增删改查
 	switch o.method {
 	case methodInsert:
-		var fieldArr, nameArr []string
-		for k, v := range newParams {
-			field := o.columnField(k)
-			if len(field) == 0 {
-				continue
-			}
-			if o.columnValidate(field, v) {
-				vStr := ValueTypeToStr(v)
-				if vStr == "" {
-					continue
-				}
-				nameArr = append(nameArr, fmt.Sprintf(`%s`, vStr))
-				fieldArr = append(fieldArr, field)
-			}
+		fieldArr, values := o.insertValues(newParams)
+		nameArr := make([]string, 0, len(values))
+		for _, arg := range values {
+			nameArr = append(nameArr, o.bindArg(arg))
 		}
-		o.sql = fmt.Sprintf(`%s %s (%s) VALUES (%s)%s`, `INSERT INTO`, o.tableName, strings.Join(fieldArr, `, `),
-			strings.Join(nameArr, `, `), o.returning)
+		o.sql = fmt.Sprintf(`%s %s (%s) VALUES (%s)%s%s`, `INSERT INTO`, o.tableName, strings.Join(fieldArr, `, `),
+			strings.Join(nameArr, `, `), o.upsertSQL(fieldArr), o.returning)
 	case methodUpdate:
 		var nameArr []string
-		for k, v := range newParams {
+		for _, k := range orderedKeys(o.fieldOrder, newParams) {
+			v := newParams[k]
 			field := o.columnField(k)
 			if len(field) == 0 {
 				continue
 			}
+			if _, allowNull := o.nullUpdateFields[field]; allowNull && isNullValue(v) {
+				nameArr = append(nameArr, field+`=NULL`)
+				continue
+			}
 			if o.columnValidate(field, v) {
-				vStr := ValueTypeToStr(v)
-				if vStr == "" {
+				arg, ok := valueToArg(v)
+				if !ok {
 					continue
 				}
-				nameArr = append(nameArr, fmt.Sprintf(`%s=%s`, field, vStr))
+				nameArr = append(nameArr, fmt.Sprintf(`%s=%s`, field, o.bindArg(arg)))
 			}
 		}
-		if len(o.updateExpressions) > 0 {
-			nameArr = append(nameArr, o.updateExpressions...)
+		if column := o.dbFields[o.version]; o.lockVersion && len(column) > 0 {
+			nameArr = append(nameArr, fmt.Sprintf(`%s=%s+1`, column, column))
 		}
+		for _, exp := range o.updateExpressions {
+			nameArr = append(nameArr, o.bindRaw(exp.Express, exp.Args))
+		}
+		conditionSQL := o.parseConditionNamed()
 		o.sql = fmt.Sprintf(`UPDATE %s SET %s%s%s`, o.tableName, strings.Join(nameArr, `, `), conditionSQL,
 			o.returning)
 	case methodSelect:
@@ -102,41 +98,30 @@ func (o *OrmModel) BuildSQL() SQLParams {
 		if len(o.requiredFields) == 0 && len(o.excludeFields) == 0 {
 			if len(o.joinTables) > 0 {
 				// 对于 JOIN 查询，给主表添加别名 t
-				fieldArr = append(fieldArr, "t.*")
-				// 添加 JOIN 表的字段
-				for _, join := range o.joinTables {
-					if len(join.SelectField) > 0 {
-						for _, field := range join.SelectField {
-							if join.Alias != "" {
-								fieldArr = append(fieldArr, fmt.Sprintf("%s.%s", join.Alias, field))
-							} else {
-								fieldArr = append(fieldArr, fmt.Sprintf("%s.%s", join.Table, field))
-							}
-						}
-					}
-				}
+				fieldArr = append(fieldArr, joinMainAlias+".*")
 			} else {
 				fieldArr = append(fieldArr, "*")
 			}
 		} else {
-			for k := range newParams {
+			for _, k := range orderedKeys(o.fieldOrder, newParams) {
 				field := o.columnField(k)
 				if len(field) == 0 {
 					continue
 				}
-				fieldArr = append(fieldArr, field)
+				fieldArr = append(fieldArr, o.qualify(field))
 			}
 		}
 
 		if len(o.joinTables) > 0 {
-			// 构建 JOIN SQL
-			tmpSql.WriteString(fmt.Sprintf(`SELECT %s %s FROM %s t %s`, o.distinct, strings.Join(fieldArr, `, `),
-				o.tableName, o.parseJoinSQL()))
+			// 构建 JOIN SQL，添加 JOIN 表的字段
+			fieldArr = append(fieldArr, o.joinSelectFields()...)
+			tmpSql.WriteString(fmt.Sprintf(`SELECT %s %s FROM %s`, o.distinct, strings.Join(fieldArr, `, `),
+				o.fromSQL()))
 		} else {
 			if o.groupBy {
 				g := strings.Join(fieldArr, `, `)
 				if len(o.groupByRaw) > 0 {
-					g += `, ` + o.groupByRaw
+					g += `, ` + o.bindRaw(o.groupByRaw, o.groupByArgs)
 				}
 				tmpSql.WriteString(fmt.Sprintf(`SELECT %s FROM %s`, g, o.tableName))
 			} else {
@@ -145,30 +130,35 @@ func (o *OrmModel) BuildSQL() SQLParams {
 			}
 		}
 
-		tmpSql.WriteString(conditionSQL)
+		tmpSql.WriteString(o.parseConditionNamed())
 		if o.groupBy {
 			tmpSql.WriteString(` GROUP BY ` + strings.Join(fieldArr, `,`))
 			// HAVING
 			if len(o.havingRaw) > 0 {
-				tmpSql.WriteString(` HAVING ` + o.havingRaw)
+				tmpSql.WriteString(` HAVING ` + o.bindRaw(o.havingRaw, o.havingArgs))
 			}
 		}
 		if len(o.orderFields) > 0 {
-			tmpSql.WriteString(` ORDER BY ` + strings.Join(o.orderFields, `,`))
-		}
-		if o.limit > 0 {
-			tmpSql.WriteString(fmt.Sprintf(` LIMIT %d`, o.limit))
-		}
-		if o.offset > 0 {
-			tmpSql.WriteString(fmt.Sprintf(` OFFSET %d`, o.offset))
+			orderFields := make([]string, 0, len(o.orderFields))
+			for _, f := range o.orderFields {
+				orderFields = append(orderFields, o.qualify(f))
+			}
+			tmpSql.WriteString(` ORDER BY ` + strings.Join(orderFields, `,`))
 		}
+		tmpSql.WriteString(o.dialect().LimitOffset(o.limit, o.offset))
 		o.sql = tmpSql.String()
 	case methodDelete:
-		o.sql = fmt.Sprintf(`%s %s %s%s`, `DELETE FROM`, o.tableName, conditionSQL, o.returning)
+		if column := o.softDeleteColumn(); len(column) > 0 {
+			// 软删除：设置删除时间
+			deletedAt, _ := valueToArg(timestampValue(reflect.TypeOf(o.params[o.softDelete]), NowFunc()))
+			set := fmt.Sprintf(`%s=%s`, column, o.bindArg(deletedAt))
+			o.sql = fmt.Sprintf(`UPDATE %s SET %s%s%s`, o.tableName, set, o.parseConditionNamed(), o.returning)
+			break
+		}
+		o.sql = fmt.Sprintf(`%s %s %s%s`, `DELETE FROM`, o.tableName, o.parseConditionNamed(), o.returning)
 	}
-	if o.log || DebugMode {
-		log.Debug().Str("sql", o.sql)
-		fmt.Println("sql:", o.sql)
+	if o.err != nil {
+		return SQLParams{Err: o.err}
 	}
 	// WITH
 	if len(o.withTable) > 0 {
@@ -178,7 +168,92 @@ func (o *OrmModel) BuildSQL() SQLParams {
 		Sql:     o.sql,
 		WithSql: o.withSQL,
 		Params:  o.params,
+		Args:    o.args,
+	}
+}
+
+// filterParams 按 ExcludeFields / Fields 过滤参与拼接的字段
+func (o *OrmModel) filterParams(params map[string]any) map[string]any {
+	newParams := make(map[string]interface{})
+	for s, i := range params {
+		newParams[s] = i
+	}
+	// 排除不参与拼接的 Key
+	if len(o.excludeFields) > 0 {
+		for k := range o.excludeFields {
+			delete(newParams, k)
+		}
+	}
+	// 保留字段
+	if len(o.requiredFields) > 0 {
+		fieldValueMap := make(map[string]interface{})
+		for k := range o.requiredFields {
+			if v, ok := newParams[k]; ok {
+				fieldValueMap[k] = v
+			}
+		}
+		newParams = fieldValueMap
+	} else if o.method == methodUpdate && len(o.updateExpressions) > 0 {
+		newParams = make(map[string]interface{})
+	}
+	o.fillTimestamps(newParams)
+	return newParams
+}
+
+// fillTimestamps 填充自动时间字段：INSERT 时 created、updated(at) 字段为空则取 NowFunc()，UPDATE 时总是更新 updated(at) 字段
+func (o *OrmModel) fillTimestamps(params map[string]any) {
+	if (o.method != methodInsert && o.method != methodUpdate) ||
+		(len(o.createdFields) == 0 && len(o.autoUpdateFields) == 0) {
+		return
+	}
+	now := NowFunc()
+	for _, k := range o.fieldOrder {
+		column := o.dbFields[k]
+		_, created := o.createdFields[column]
+		_, updated := o.autoUpdateFields[column]
+		if _, excluded := o.excludeFields[k]; excluded || (!created && !updated) {
+			continue
+		}
+		v := o.params[k]
+		if o.method == methodInsert {
+			if arg, ok := valueToArg(v); ok && !isZeroArg(arg) {
+				continue
+			}
+		} else if !updated || o.hasUpdateExpression(column) {
+			continue
+		}
+		params[k] = timestampValue(reflect.TypeOf(v), now)
+	}
+}
+
+// hasUpdateExpression SetField 等更新表达式中是否已设置 column
+func (o *OrmModel) hasUpdateExpression(column string) bool {
+	for _, exp := range o.updateExpressions {
+		if strings.HasPrefix(exp.Express, column+`=`) {
+			return true
+		}
+	}
+	return false
+}
+
+// insertValues INSERT 的列与对应的参数，空值字段按 columnValidate 规则跳过
+func (o *OrmModel) insertValues(params map[string]any) (fieldArr []string, values []any) {
+	for _, k := range orderedKeys(o.fieldOrder, params) {
+		v := params[k]
+		field := o.columnField(k)
+		if len(field) == 0 {
+			continue
+		}
+		if o.columnValidate(field, v) {
+			arg, ok := valueToArg(v)
+			if !ok {
+				continue
+			}
+			fieldArr = append(fieldArr, field)
+			values = append(values, arg)
+		}
 	}
+	return fieldArr, values
 }
 
 // FullSQL SQL+WithSQL
@@ -196,30 +271,12 @@ func (o *OrmModel) FullSQL() SQLParams {
 
 // NamedExec 执行带命名参数的 SQL 语句
 func NamedExec(sqlStr string, params map[string]interface{}) error {
-	var err error
-	var result dbsql.Result
-	f := func() {
-		var count int64
-		if SqlxDB == nil {
-			err = errors.New(`SqlxDB *sqlx.DB is nil`)
-		}
-		defer func() {
-			if e := recover(); e != nil {
-				err = errors.New(e.(*pq.Error).Message)
-				log.Error().Msg(e.(*pq.Error).Message)
-			}
-		}()
-		result, err = SqlxDB.NamedExec(sqlStr, params)
-		if err != nil {
-			return
-		}
-		count, err = result.RowsAffected()
-		if count == 0 && err == nil {
-			err = errors.New(`影响行数为0`)
-		}
-	}
-	f()
-	return err
+	return defaultDB().NamedExec(sqlStr, params)
+}
+
+// NamedExecContext 使用 ctx 执行带命名参数的 SQL 语句
+func NamedExecContext(ctx context.Context, sqlStr string, params map[string]interface{}) error {
+	return defaultDB().NamedExecContext(ctx, sqlStr, params)
 }
 
 func O() *OrmModel {
@@ -228,88 +285,49 @@ func O() *OrmModel {
 
 // NamedQuery 执行带命名参数的 SQL 查询并映射结果
 func NamedQuery(query string, params any, dest any) error {
-	fieldMap, _ := StructToMap(params)
-	keys := make([]string, 0, len(fieldMap))
-	for k := range fieldMap {
-		keys = append(keys, k)
-	}
-	sort.Slice(keys, func(i, j int) bool {
-		return len(keys[i]) > len(keys[j])
-	})
-	for _, k := range keys {
-		name := fmt.Sprintf(`:%s`, k)
-		v := fieldMap[k]
-		newValue := ""
-		switch v.(type) {
-		case string:
-			newValue = fmt.Sprintf(`'%v'`, v)
-		default:
-			newValue = fmt.Sprintf("%v", v)
-		}
-		if len(newValue) == 0 {
-			continue
-		}
-		query = strings.ReplaceAll(query, name, newValue)
-	}
-	return Query(query, dest)
+	return defaultDB().NamedQuery(query, params, dest)
+}
+
+// NamedQueryContext 使用 ctx 执行带命名参数的 SQL 查询并映射结果
+func NamedQueryContext(ctx context.Context, query string, params any, dest any) error {
+	return defaultDB().NamedQueryContext(ctx, query, params, dest)
 }
 
 // NamedQueryWithMap 执行带命名参数的 SQL 查询并映射结果
 func NamedQueryWithMap(query string, fieldMap map[string]any, dest any) error {
-	keys := make([]string, 0, len(fieldMap))
-	for k := range fieldMap {
-		keys = append(keys, k)
-	}
-	sort.Slice(keys, func(i, j int) bool {
-		return len(keys[i]) > len(keys[j])
-	})
-	for _, k := range keys {
-		name := fmt.Sprintf(`:%s`, k)
-		v := fieldMap[k]
-		newValue := ""
-		switch v.(type) {
-		case string:
-			newValue = fmt.Sprintf(`'%v'`, v)
-		default:
-			newValue = fmt.Sprintf("%v", v)
-		}
-		if len(newValue) == 0 {
-			continue
-		}
-		query = strings.ReplaceAll(query, name, newValue)
-	}
-	return Query(query, dest)
+	return defaultDB().NamedQueryWithMap(query, fieldMap, dest)
 }
 
-func Query(query string, dest any) error {
-	var rows *sqlx.Rows
-	var err error
-	rows, err = SqlxDB.Queryx(query)
-	if err != nil {
-		return err
-	}
-	return rowsMapScan(rows, dest)
+// NamedQueryWithMapContext 使用 ctx 执行带命名参数的 SQL 查询并映射结果
+func NamedQueryWithMapContext(ctx context.Context, query string, fieldMap map[string]any, dest any) error {
+	return defaultDB().NamedQueryWithMapContext(ctx, query, fieldMap, dest)
+}
+
+// Query 执行 SQL 查询并将第一行映射到 dest，args 按驱动占位符顺序绑定
+func Query(query string, dest any, args ...any) error {
+	return defaultDB().Query(query, dest, args...)
 }
 
-func rowsMapScan(rows *sqlx.Rows, dest any) error {
-	o := O()
-	fieldMap := make(map[string]interface{})
+// QueryContext 使用 ctx 执行 SQL 查询并将第一行映射到 dest
+func QueryContext(ctx context.Context, query string, dest any, args ...any) error {
+	return defaultDB().QueryContext(ctx, query, dest, args...)
+}
+
+// rowsMapScan 将第一行映射到 dest 并关闭 rows
+func rowsMapScan(ctx context.Context, rows *sqlx.Rows, dest any) error {
 	defer func() { _ = rows.Close() }()
-	if rows.Next() {
-		if o.err = rows.MapScan(fieldMap); o.err != nil {
-			return o.err
-		}
-	}
 	t := reflect.TypeOf(dest)
-	if t.Kind() != reflect.Ptr {
-		o.err = errors.New(`error: t.Kind() != reflect.Prt`)
-	} else {
-		t = t.Elem()
-	}
-	v := reflect.ValueOf(dest)
-	v = reflect.Indirect(v)
-	o.err = o.bindRow(t, v, fieldMap)
-	return o.err
+	if t == nil || t.Kind() != reflect.Ptr {
+		return errors.New(`error: t.Kind() != reflect.Prt`)
+	}
+	if !rows.Next() {
+		return rows.Err()
+	}
+	sc, err := newRowScanner(ctx, rows, t.Elem())
+	if err != nil {
+		return err
+	}
+	return sc.scan(rows, reflect.Indirect(reflect.ValueOf(dest)))
 }
 
 // 对列值进行校验是否可以执行 INSERT ｜ UPDATE
@@ -328,34 +346,45 @@ func (o *OrmModel) columnValidate(column string, value any) bool {
 		}
 	//case map[string]interface{}:
 	case []byte:
-		if len(columnValue) > 0 {
-			o.params[column] = string(columnValue)
-			return true
+		return len(columnValue) > 0
+	case time.Time:
+		return !columnValue.IsZero() || allowEmpty
+	case *time.Time:
+		return columnValue != nil
+	case driver.Valuer:
+		val, err := driverValue(columnValue)
+		if err != nil || val == nil {
+			return false
 		}
+		return o.columnValidate(column, val)
 	default:
 		jsonStr, err := jsoniter.MarshalToString(columnValue)
 		if err != nil {
 			fmt.Println(fmt.Sprintf("error: methodInsert not processed, because value: %v", columnValue))
 			return false
 		}
-		if jsonStr == `null` {
-			return false
-		}
-		o.params[column] = jsonStr
-		return true
+		return jsonStr != `null`
 	}
 	return false
 }
 
+// RETURNING 执行 INSERT/UPDATE/DELETE 并将 RETURNING 的列映射到 single 或 list，与 Exec 一样调用实体的 Before/After 钩子
 func (o *OrmModel) RETURNING(single any, list any, jsonTag ...string) error {
-	if SqlxDB.DriverName() != "postgres" {
-		panic("RETURNING方法不支持")
+	if d := o.dialect(); !d.SupportsReturning() {
+		o.err = fmt.Errorf("RETURNING is not supported by %s", d.Name())
+		return o.err
 	}
 	if (single != nil && list != nil) || (single == nil && list == nil) {
 		err := errors.New("Choose one from {single} and {list}")
 		log.Err(err).Msg("RETURNING")
 		return err
 	}
+	if o.err != nil {
+		return o.err
+	}
+	if o.err = o.beforeHook(); o.err != nil {
+		return o.err
+	}
 	var columnArr []string
 	for _, j := range jsonTag {
 		column := o.columnField(j)
@@ -369,13 +398,28 @@ func (o *OrmModel) RETURNING(single any, list any, jsonTag ...string) error {
 	if len(columnArr) > 0 {
 		o.returning = ` RETURNING ` + strings.Join(columnArr, ",")
 	}
+	var err error
+	found := true
 	if single != nil {
-		return o.One(single)
+		found, err = o.one(single)
+	} else if err = o.Many(list); err == nil {
+		found = reflect.Indirect(reflect.ValueOf(list)).Len() > 0
 	}
-	return o.Many(list)
+	if err == nil && !found && o.lockVersion {
+		// 版本不一致，记录已被其他事务修改
+		err = ErrStaleObject
+		o.err = err
+	}
+	if err == nil {
+		err = o.afterHook()
+		o.err = err
+	}
+	return err
 }
 
 // WherePK 使用dbTag里包含pk字符的jsonTag的字段进行查询。 db:"columnName,pk"
+//
+// UPDATE 时若有 db:"columnName,version" 字段，追加版本条件并递增版本，影响行数为 0 时返回 ErrStaleObject，RETURNING 没有返回记录时同样返回
 func (o *OrmModel) WherePK() *OrmModel {
 	if len(o.pk) > 0 {
 		o.conditionFields[o.pk] = emptyKey{}
@@ -383,37 +427,33 @@ func (o *OrmModel) WherePK() *OrmModel {
 			o.excludeFields[o.pk] = emptyKey{}
 		}
 		digest := md5.Sum([]byte(o.pk))
-		o.namedCGArr[hex.EncodeToString(digest[:])] = ConditionGroup{JsonTags: []string{o.pk}, cType: cgTypeAndOr}
+		o.addCondition(hex.EncodeToString(digest[:]), ConditionGroup{JsonTags: []string{o.pk}, cType: cgTypeAndOr})
+	}
+	if o.method == methodUpdate && len(o.version) > 0 {
+		o.lockVersion = true
+		o.excludeFields[o.version] = emptyKey{}
+		digest := md5.Sum([]byte(o.version))
+		o.addCondition(hex.EncodeToString(digest[:]), ConditionGroup{JsonTags: []string{o.version}, cType: cgTypeAndOr})
 	}
 	return o
 }
 
 // SetField UPDATE 设置字段值
 func (o *OrmModel) SetField(jsonTag string, arg any) *OrmModel {
-	var expression string
 	column := o.columnField(jsonTag)
 	if len(column) > 0 {
 		delete(o.requiredFields, column)
-		switch t := arg.(type) {
-		case string:
-			expression = fmt.Sprintf(`%s='%s'`, column, t)
-		case nil:
-			expression = fmt.Sprintf(`%s=NULL`, column)
-		default:
-			expression = fmt.Sprintf(`%s=%v`, column, t)
+		if arg == nil {
+			o.updateExpressions = append(o.updateExpressions, Raw(column+`=NULL`))
+		} else {
+			o.updateExpressions = append(o.updateExpressions, Raw(column+`=$1`, arg))
 		}
-		o.updateExpressions = append(o.updateExpressions, expression)
 	}
 	return o
 }
 
 func RawNamedSQL(sql string, params any) *OrmModel {
-	o := RawSQL(sql)
-	o.setMethod(o.method, params)
-	if params != nil {
-		o.namedExec = true
-	}
-	return o
+	return defaultDB().RawNamedSQL(sql, params)
 }
 
 func ConvertArray[T int | string](array []T) []string {
@@ -1,6 +1,7 @@
 package mworm
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -40,44 +41,103 @@ var (
 	ErrNilDB           = &Error{Code: 1002, Message: "database connection is nil"}
 	ErrEmptySQL        = &Error{Code: 1003, Message: "SQL statement is empty"}
 	ErrNoEffect        = &Error{Code: 1004, Message: "no rows affected"}
+	ErrNotFound        = &Error{Code: 1005, Message: "record not found"}
+	ErrStaleObject     = &Error{Code: 1006, Message: "record has been modified by another transaction"}
 )
 
+// pageFiller 分页结果，由 *PageResult[T] 实现
+type pageFiller interface {
+	fillPage(page, pageSize int)
+}
+
+func (pr *PageResult[T]) fillPage(page, pageSize int) {
+	if len(pr.List) > 0 {
+		pr.Page = page
+		pr.PageSize = pageSize
+		pr.TotalPage = pr.CalcTotalPage()
+	}
+}
+
 // PAGE 分页查询方法，支持排除指定的json tag字段
 func PAGE[T ORMInterface](entity T, page, pageSize int, excludeTags []string, cgs ...ConditionGroup) (PageResult[T], error) {
 	return DebugPAGE(entity, false, page, pageSize, excludeTags, cgs...)
 }
 
+// PAGEContext 使用 ctx 的分页查询方法
+func PAGEContext[T ORMInterface](ctx context.Context, entity T, page, pageSize int, excludeTags []string, cgs ...ConditionGroup) (PageResult[T], error) {
+	var dest PageResult[T]
+	err := queryPage(defaultDB().SELECT(entity).Ctx(ctx), false, page, pageSize, excludeTags, &dest, cgs...)
+	return dest, err
+}
+
 // DebugPAGE 分页查询方法，支持调试和排除指定的json tag字段
 func DebugPAGE[T ORMInterface](entity T, debug bool, page, pageSize int, excludeTags []string, cgs ...ConditionGroup) (PageResult[T], error) {
 	var dest PageResult[T]
+	err := queryPage(defaultDB().SELECT(entity), debug, page, pageSize, excludeTags, &dest, cgs...)
+	return dest, err
+}
+
+// PAGE 分页查询方法，dest 为 *PageResult[T]
+func (d *DB) PAGE(entity ORMInterface, page, pageSize int, excludeTags []string, dest any, cgs ...ConditionGroup) error {
+	return d.PAGEContext(context.Background(), entity, page, pageSize, excludeTags, dest, cgs...)
+}
+
+// PAGEContext 使用 ctx 的分页查询方法，dest 为 *PageResult[T]
+func (d *DB) PAGEContext(ctx context.Context, entity ORMInterface, page, pageSize int, excludeTags []string, dest any, cgs ...ConditionGroup) error {
+	pf, ok := dest.(pageFiller)
+	if !ok {
+		return fmt.Errorf("dest must be *PageResult[T], got %T", dest)
+	}
+	return queryPage(d.SELECT(entity).Ctx(ctx), false, page, pageSize, excludeTags, pf, cgs...)
+}
+
+// PAGE 在事务中分页查询，dest 为 *PageResult[T]
+func (tx *Tx) PAGE(entity ORMInterface, page, pageSize int, excludeTags []string, dest any, cgs ...ConditionGroup) error {
+	pf, ok := dest.(pageFiller)
+	if !ok {
+		return fmt.Errorf("dest must be *PageResult[T], got %T", dest)
+	}
+	return queryPage(tx.SELECT(entity), false, page, pageSize, excludeTags, pf, cgs...)
+}
+
+// queryPage 以 SELECT 构造器 orm 执行分页查询，orm 决定使用的连接、事务与 ctx
+func queryPage(orm *OrmModel, debug bool, page, pageSize int, excludeTags []string, dest pageFiller, cgs ...ConditionGroup) (err error) {
 	if pageSize < 1 {
-		return dest, ErrInvalidPageSize
+		return ErrInvalidPageSize
 	}
-	orm := SELECT(entity).Where(cgs...).Log(debug)
+	entity := orm.entity
+	orm = orm.Where(cgs...).Log(debug)
+	end := orm.startSpan("PAGE")
+	defer func() { end(err) }()
 	sqlParams := orm.BuildSQL()
-	//fmt.Println(tableSql)
+	if sqlParams.Err != nil {
+		return sqlParams.Err
+	}
+	dialect := orm.dialect()
+	alias := dialect.Quote(`row`)
 	var jsonKeys string
 	if len(excludeTags) > 0 {
-		jsonKeys = JsonTagToJsonbKeys(entity, `row`, excludeTags...)
+		jsonKeys = JsonTagToJsonbKeys(entity, alias, excludeTags...)
 	} else {
-		jsonKeys = JsonbBuildObjString(entity, `row`)
+		jsonKeys = JsonbBuildObjString(entity, alias)
 	}
 	sql := `
 	WITH t AS (%s),
 	t1 AS (SELECT count(*) as total FROM t),
-	t2 AS (SELECT jsonb_agg(jsonb_build_object(%s)) list FROM (SELECT * FROM t LIMIT %d OFFSET %d) row),
+	t2 AS (SELECT %s list FROM (SELECT * FROM t%s) %s),
 	t3 AS (SELECT t2.*, t1.* FROM t2 CROSS JOIN t1)
-	SELECT * FROM t3;
+	SELECT * FROM t3
 	`
-	sql = fmt.Sprintf(sql, sqlParams.Sql, jsonKeys, pageSize, (page-1)*pageSize)
+	sql = fmt.Sprintf(sql, sqlParams.Sql, dialect.JSONAgg(dialect.JSONBuildObject(jsonKeys)),
+		dialect.LimitOffset(int64(pageSize), int64((page-1)*pageSize)), alias)
 	//fmt.Println(sql)
-	if err := NamedQueryWithMap(sql, orm.params, &dest); err != nil {
-		return dest, err
+	e := orm.executor()
+	if e == nil {
+		return ErrNilDB
 	}
-	if len(dest.List) > 0 {
-		dest.Page = page
-		dest.PageSize = pageSize
-		dest.TotalPage = dest.CalcTotalPage()
+	if err = queryContext(orm.context(), e, sql, dest, sqlParams.Args...); err != nil {
+		return err
 	}
-	return dest, nil
+	dest.fillPage(page, pageSize)
+	return nil
 }
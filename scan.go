@@ -0,0 +1,252 @@
+package mworm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// isValueType t 是否作为单个列值处理：time.Time 或实现了 driver.Valuer / sql.Scanner
+func isValueType(t reflect.Type) bool {
+	return t == timeType || t.Implements(valuerType) || reflect.PointerTo(t).Implements(valuerType) ||
+		reflect.PointerTo(t).Implements(scannerType)
+}
+
+// scanPlan 每一列对应的结构体字段索引路径，nil 表示忽略该列
+type scanPlan [][]int
+
+type scanPlanKey struct {
+	t       reflect.Type
+	columns string
+}
+
+// scanPlans 按类型与列缓存 scanPlan，并发安全
+var scanPlans sync.Map
+
+// loadScanPlan 返回结构体类型 t 与 columns 的 scanPlan
+func loadScanPlan(t reflect.Type, columns []string) scanPlan {
+	key := scanPlanKey{t: t, columns: strings.Join(columns, "\x00")}
+	if plan, ok := scanPlans.Load(key); ok {
+		return plan.(scanPlan)
+	}
+	index := structFieldIndex(t)
+	plan := make(scanPlan, len(columns))
+	for i, column := range columns {
+		plan[i] = index[column]
+	}
+	actual, _ := scanPlans.LoadOrStore(key, plan)
+	return actual.(scanPlan)
+}
+
+// structFieldIndex 结构体 db tag 对应的字段索引路径，嵌入与 inline 结构体按 nestedPrefix 展开，同名列取外层字段
+func structFieldIndex(t reflect.Type) map[string][]int {
+	index := make(map[string][]int)
+	walkFieldIndex(t, nil, "", index)
+	return index
+}
+
+func walkFieldIndex(t reflect.Type, parent []int, columnPrefix string, index map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && (!field.Anonymous || field.Type.Kind() == reflect.Ptr) {
+			// 非导出的嵌入结构体指针无法分配
+			continue
+		}
+		path := append(parent[:len(parent):len(parent)], i)
+		if prefix, _, ok := nestedPrefix(field); ok {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			walkFieldIndex(ft, path, columnPrefix+prefix, index)
+			continue
+		}
+		dbTag := field.Tag.Get(TagName)
+		name := strings.TrimSpace(strings.Split(dbTag, ",")[0])
+		if len(name) == 0 || name == "-" || !field.IsExported() {
+			continue
+		}
+		name = columnPrefix + name
+		if old, ok := index[name]; ok && len(old) <= len(path) {
+			continue
+		}
+		index[name] = path
+	}
+}
+
+// fieldByIndex 与 reflect.Value.FieldByIndex 相同，路径上的 nil 指针会被分配
+func fieldByIndex(v reflect.Value, path []int) reflect.Value {
+	for i, x := range path {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// rowScanner 按 scanPlan 将结果集逐行直接扫描到结构体字段
+type rowScanner struct {
+	ctx       context.Context
+	afterFind bool // 目标类型实现了 AfterFindInterface
+	plan      scanPlan
+	scalar    bool           // 非结构体，映射第一列
+	fields    []fieldScanner // 与列一一对应
+	dest      []any          // 传给 rows.Scan 的参数
+}
+
+// newRowScanner 为结果集与目标类型 t 创建 rowScanner
+func newRowScanner(ctx context.Context, rows *sqlx.Rows, t reflect.Type) (*rowScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	s := &rowScanner{
+		ctx:       ctx,
+		afterFind: reflect.PointerTo(t).Implements(afterFindType),
+		scalar:    t.Kind() != reflect.Struct || isValueType(t),
+		fields:    make([]fieldScanner, len(columns)),
+		dest:      make([]any, len(columns)),
+	}
+	if !s.scalar {
+		s.plan = loadScanPlan(t, columns)
+	}
+	discard := new(any)
+	for i := range columns {
+		if (s.scalar && i > 0) || (!s.scalar && s.plan[i] == nil) {
+			s.dest[i] = discard
+			continue
+		}
+		s.dest[i] = &s.fields[i]
+	}
+	return s, nil
+}
+
+// scan 将当前行扫描到 v
+func (s *rowScanner) scan(rows *sqlx.Rows, v reflect.Value) error {
+	if s.scalar {
+		if len(s.fields) > 0 {
+			s.fields[0].field = v
+		}
+	} else {
+		for i, path := range s.plan {
+			if path != nil {
+				s.fields[i].field = fieldByIndex(v, path)
+			}
+		}
+	}
+	if err := rows.Scan(s.dest...); err != nil {
+		return err
+	}
+	if s.afterFind && v.CanAddr() {
+		return v.Addr().Interface().(AfterFindInterface).AfterFind(s.ctx)
+	}
+	return nil
+}
+
+// fieldScanner 实现 sql.Scanner，将列值写入结构体字段
+type fieldScanner struct {
+	field reflect.Value
+}
+
+// Scan 字段实现 sql.Scanner 时交由其处理；驱动类型与字段类型一致时直接赋值，否则使用 setStructValue 转换，NULL 保持零值，指针字段为 nil
+func (s *fieldScanner) Scan(src any) error {
+	f := s.field
+	if f.CanAddr() {
+		if sc, ok := f.Addr().Interface().(sql.Scanner); ok {
+			return sc.Scan(src)
+		}
+	}
+	if f.Kind() == reflect.Ptr {
+		// 指针字段：NULL 置为 nil，否则分配新值后按元素类型扫描
+		if src == nil {
+			f.SetZero()
+			return nil
+		}
+		elem := reflect.New(f.Type().Elem())
+		if err := (&fieldScanner{field: elem.Elem()}).Scan(src); err != nil {
+			return err
+		}
+		f.Set(elem)
+		return nil
+	}
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case int64:
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f.SetInt(v)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if v < 0 || f.OverflowUint(uint64(v)) {
+				return fmt.Errorf("error: value %d overflows %s", v, f.Type())
+			}
+			f.SetUint(uint64(v))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			f.SetFloat(float64(v))
+			return nil
+		case reflect.Bool: // MySQL tinyint(1) 与 SQLite 的布尔值以整数返回
+			f.SetBool(v != 0)
+			return nil
+		}
+	case uint64:
+		switch f.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f.SetUint(v)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v > math.MaxInt64 || f.OverflowInt(int64(v)) {
+				return fmt.Errorf("error: value %d overflows %s", v, f.Type())
+			}
+			f.SetInt(int64(v))
+			return nil
+		}
+	case float64:
+		switch f.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f.SetFloat(v)
+			return nil
+		}
+	case string:
+		if f.Kind() == reflect.String {
+			f.SetString(v)
+			return nil
+		}
+	case []byte:
+		switch {
+		case f.Kind() == reflect.String:
+			f.SetString(string(v))
+			return nil
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Uint8:
+			// 驱动复用 []byte，需要复制
+			f.SetBytes(bytes.Clone(v))
+			return nil
+		}
+	case bool:
+		if f.Kind() == reflect.Bool {
+			f.SetBool(v)
+			return nil
+		}
+	}
+	return setStructValue(f, src)
+}
@@ -0,0 +1,297 @@
+package mworm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type TestNullable struct {
+	ID    int            `json:"id" db:"id,pk"`
+	Name  sql.NullString `json:"name" db:"name"`
+	Score sql.NullInt64  `json:"score" db:"score"`
+	Tags  pq.StringArray `json:"tags" db:"tags"`
+}
+
+func (TestNullable) TableName() string { return "test_nullable" }
+
+func TestScanPlan(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"id", "name", "type", "images", "extra"}
+	f.rows = [][]driver.Value{
+		{int64(1), []byte("a"), []byte("7"), []byte(`["x","y"]`), "ignored"},
+		{int64(2), nil, int64(3), nil, nil},
+	}
+	var list []*TestTable
+	if err := db.SELECT(TestTable{}).Many(&list); err != nil {
+		t.Fatal(err)
+	}
+	want := []*TestTable{{ID: 1, Name: "a", Type: 7, Images: []string{"x", "y"}}, {ID: 2, Type: 3}}
+	if !reflect.DeepEqual(list, want) {
+		t.Fatalf("list = %+v %+v", list[0], list[1])
+	}
+
+	key := scanPlanKey{t: reflect.TypeOf(TestTable{}), columns: strings.Join(f.columns, "\x00")}
+	plan, ok := scanPlans.Load(key)
+	if !ok {
+		t.Fatal("scan plan not cached")
+	}
+	if p := plan.(scanPlan); !reflect.DeepEqual(p[0], []int{0}) || p[4] != nil {
+		t.Fatalf("plan = %v", p)
+	}
+}
+
+func TestScannerValuer(t *testing.T) {
+	db, f := newFakeDB(t)
+	row := TestNullable{ID: 1, Name: sql.NullString{String: "a", Valid: true}, Tags: pq.StringArray{"x", "y"}}
+	if err := db.INSERT(row).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "test_nullable" (id, name, tags) VALUES ($1, $2, $3)`; f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	if want := []driver.Value{int64(1), "a", `{"x","y"}`}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+	sp := db.SELECT(row).Where(AndAuto("name", "score")).BuildSQL()
+	if want := `SELECT  * FROM "test_nullable" WHERE (name=$1)`; sp.Sql != want {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+
+	f.columns = []string{"id", "name", "score", "tags"}
+	f.rows = [][]driver.Value{{int64(2), nil, int64(5), []byte(`{a,b}`)}}
+	got, err := FetchOne[TestNullable](db.SELECT(TestNullable{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TestNullable{ID: 2, Score: sql.NullInt64{Int64: 5, Valid: true}, Tags: pq.StringArray{"a", "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("row = %+v", got)
+	}
+}
+
+func benchmarkRows(b *testing.B) (*DB, *fakeDB) {
+	db, f := newFakeDB(b)
+	f.columns = []string{"id", "name", "type", "created_at", "images"}
+	for i := 0; i < 100; i++ {
+		f.rows = append(f.rows, []driver.Value{int64(i), "name", int64(i % 3), "2024-01-01", []byte(`["a"]`)})
+	}
+	return db, f
+}
+
+// BenchmarkManyScan 直接扫描到结构体字段
+func BenchmarkManyScan(b *testing.B) {
+	db, _ := benchmarkRows(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var list []TestTable
+		if err := db.SELECT(TestTable{}).Many(&list); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkManyMapScan MapScan 后逐字段赋值，对照直接扫描的分配次数
+func BenchmarkManyMapScan(b *testing.B) {
+	db, _ := benchmarkRows(b)
+	index := structFieldIndex(reflect.TypeOf(TestTable{}))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var list []TestTable
+		rows, err := db.Sqlx().Queryx(`SELECT * FROM test_table`)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for rows.Next() {
+			fieldMap := make(map[string]interface{})
+			if err = rows.MapScan(fieldMap); err != nil {
+				b.Fatal(err)
+			}
+			var row TestTable
+			v := reflect.ValueOf(&row).Elem()
+			for column, path := range index {
+				if err = setStructValue(v.FieldByIndex(path), fieldMap[column]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			list = append(list, row)
+		}
+		_ = rows.Close()
+	}
+}
+
+type TestPointer struct {
+	ID     int        `json:"id" db:"id,pk"`
+	Name   *string    `json:"name" db:"name"`
+	Score  *int64     `json:"score" db:"score,nu"`
+	Rate   *float64   `json:"rate" db:"rate"`
+	Active *bool      `json:"active" db:"active"`
+	DoneAt *time.Time `json:"doneAt" db:"done_at"`
+}
+
+func (TestPointer) TableName() string { return "test_pointer" }
+
+func TestPointerField(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"id", "name", "score", "rate", "active", "done_at"}
+	done := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	f.rows = [][]driver.Value{
+		{int64(1), []byte("a"), int64(5), 1.5, true, done},
+		{int64(2), nil, nil, nil, nil, nil},
+	}
+	list, err := Fetch[TestPointer](db.SELECT(TestPointer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := list[0]
+	if r.Name == nil || *r.Name != "a" || r.Score == nil || *r.Score != 5 || r.Rate == nil || *r.Rate != 1.5 ||
+		r.Active == nil || !*r.Active || r.DoneAt == nil || !r.DoneAt.Equal(done) {
+		t.Fatalf("row = %+v", r)
+	}
+	if r = list[1]; r.Name != nil || r.Score != nil || r.Rate != nil || r.Active != nil || r.DoneAt != nil {
+		t.Fatalf("row = %+v", r)
+	}
+
+	zero, name := false, "b"
+	row := TestPointer{ID: 1, Name: &name, Active: &zero}
+	if err = db.UPDATE(row).WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "test_pointer" SET name=$1, score=NULL, active=$2 WHERE (id=$3)`; f.stmts[1] != want {
+		t.Fatalf("sql = %s", f.stmts[1])
+	}
+	if want := []driver.Value{"b", false, int64(1)}; !reflect.DeepEqual(f.args[1], want) {
+		t.Fatalf("args = %#v", f.args[1])
+	}
+	if err = db.UPDATE(row).AllowNull("doneAt").WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "test_pointer" SET name=$1, score=NULL, active=$2, done_at=NULL WHERE (id=$3)`; f.stmts[2] != want {
+		t.Fatalf("sql = %s", f.stmts[2])
+	}
+}
+
+type TestBase struct {
+	ID        int    `json:"id" db:"id,pk"`
+	CreatedAt string `json:"createdAt" db:"created_at"`
+}
+
+type TestAudit struct {
+	UpdatedBy string `json:"updatedBy" db:"updated_by"`
+}
+
+type TestAddress struct {
+	City string `json:"city" db:"city"`
+	Zip  string `json:"zip" db:"zip"`
+}
+
+type TestNested struct {
+	TestBase
+	*TestAudit
+	Name string       `json:"name" db:"name"`
+	Home TestAddress  `json:"home" db:"home_,inline"`
+	Work *TestAddress `json:"work" db:"work_,inline"`
+}
+
+func (TestNested) TableName() string { return "test_nested" }
+
+func TestNestedStruct(t *testing.T) {
+	db, f := newFakeDB(t)
+	row := TestNested{TestBase: TestBase{ID: 1}, Name: "a", Home: TestAddress{City: "x"}}
+	if err := db.INSERT(row).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "test_nested" (id, name, home_city) VALUES ($1, $2, $3)`; f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	sp := db.UPDATE(row).Fields("name").Where(Eq("home.city", "y")).WherePK().BuildSQL()
+	if want := `UPDATE "test_nested" SET name=$1 WHERE home_city=$2 AND (id=$3)`; sp.Sql != want {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+
+	f.columns = []string{"id", "created_at", "updated_by", "name", "home_city", "home_zip", "work_city", "work_zip"}
+	f.rows = [][]driver.Value{{int64(2), "c", "u", "b", "x", "1", "w", nil}}
+	got, err := FetchOne[TestNested](db.SELECT(TestNested{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TestNested{TestBase: TestBase{ID: 2, CreatedAt: "c"}, TestAudit: &TestAudit{UpdatedBy: "u"}, Name: "b",
+		Home: TestAddress{City: "x", Zip: "1"}, Work: &TestAddress{City: "w"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("row = %+v", got)
+	}
+}
+
+type TestConvert struct {
+	ID     int    `json:"id" db:"id,pk"`
+	Active bool   `json:"active" db:"active"`
+	Count  uint   `json:"count" db:"count"`
+	Size   int32  `json:"size" db:"size"`
+	Flag   *bool  `json:"flag" db:"flag"`
+	Small  uint8  `json:"small" db:"small"`
+	Note   string `json:"note" db:"note"`
+}
+
+func (TestConvert) TableName() string { return "test_convert" }
+
+func TestScanConversion(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"id", "active", "count", "size", "flag", "small"}
+	f.rows = [][]driver.Value{{int64(1), int64(1), int64(7), uint64(9), int64(0), uint64(3)}}
+	got, err := FetchOne[TestConvert](db.SELECT(TestConvert{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.Count != 7 || got.Size != 9 || got.Flag == nil || *got.Flag || got.Small != 3 {
+		t.Fatalf("row = %+v", got)
+	}
+
+	// MySQL 文本协议以 []byte 返回 TINYINT(1) 与无符号整数
+	f.rows = [][]driver.Value{{int64(1), []byte("1"), []byte("42"), []byte("5"), []byte("0"), []byte("255")}}
+	if got, err = FetchOne[TestConvert](db.SELECT(TestConvert{})); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.Count != 42 || got.Size != 5 || got.Flag == nil || *got.Flag || got.Small != 255 {
+		t.Fatalf("row = %+v", got)
+	}
+	f.rows = [][]driver.Value{{int64(1), "true", "7", int64(0), "1", "3"}}
+	if got, err = FetchOne[TestConvert](db.SELECT(TestConvert{})); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.Count != 7 || got.Flag == nil || !*got.Flag || got.Small != 3 {
+		t.Fatalf("row = %+v", got)
+	}
+
+	// 类型不匹配或溢出返回错误而不是 panic
+	for _, row := range [][]driver.Value{
+		{int64(1), "yes", int64(0), int64(0), nil, int64(0)},
+		{int64(1), int64(0), int64(-1), int64(0), nil, int64(0)},
+		{int64(1), int64(0), int64(0), uint64(1 << 40), nil, int64(0)},
+		{int64(1), int64(0), int64(0), int64(0), nil, int64(256)},
+		{int64(1), int64(0), int64(0), int64(0), 1.5, int64(0)},
+		{int64(1), []byte("2"), int64(0), int64(0), nil, int64(0)},
+		{int64(1), int64(0), int64(0), int64(0), nil, []byte("256")},
+		{int64(1), int64(0), []byte("-1"), int64(0), nil, int64(0)},
+	} {
+		f.rows = [][]driver.Value{row}
+		if _, err = FetchOne[TestConvert](db.SELECT(TestConvert{})); err == nil {
+			t.Fatalf("row %v: expected error", row)
+		}
+		if n := f.openRows(); n != 0 {
+			t.Fatalf("open rows = %d", n)
+		}
+	}
+
+	var note TestConvert
+	if err = setStructValue(reflect.ValueOf(&note).Elem().Field(6), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err = setStructValue(reflect.ValueOf(&note).Elem().Field(1), time.Now()); err == nil {
+		t.Fatal("expected error for time.Time into bool")
+	}
+}
@@ -0,0 +1,20 @@
+package mworm
+
+// Unscoped 不排除已软删除的记录，DELETE 时物理删除
+func (o *OrmModel) Unscoped() *OrmModel {
+	o.unscoped = true
+	return o
+}
+
+// HardDelete 物理删除，等同 Unscoped
+func (o *OrmModel) HardDelete() *OrmModel {
+	return o.Unscoped()
+}
+
+// softDeleteColumn 生效的软删除字段，db tag 带 softdelete 标记且未调用 Unscoped 时返回列名
+func (o *OrmModel) softDeleteColumn() string {
+	if len(o.softDelete) == 0 || o.unscoped || o.method == methodInsert || o.rawSQL {
+		return ""
+	}
+	return o.dbFields[o.softDelete]
+}
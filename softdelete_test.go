@@ -0,0 +1,63 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type TestSoft struct {
+	ID        int        `json:"id" db:"id,pk"`
+	Name      string     `json:"name" db:"name"`
+	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at,softdelete"`
+}
+
+func (TestSoft) TableName() string { return "test_soft" }
+
+func TestSoftDelete(t *testing.T) {
+	db, f := newFakeDB(t)
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	NowFunc = func() time.Time { return now }
+	t.Cleanup(func() { NowFunc = time.Now })
+
+	if err := db.DELETE(TestSoft{ID: 1}).WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "test_soft" SET deleted_at=$1 WHERE (id=$2) AND deleted_at IS NULL`; f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	if want := []driver.Value{now, int64(1)}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+	if err := db.DELETE(TestSoft{ID: 1}).WherePK().HardDelete().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `DELETE FROM "test_soft"  WHERE (id=$1)`; f.stmts[1] != want {
+		t.Fatalf("sql = %s", f.stmts[1])
+	}
+
+	cases := []struct {
+		o    *OrmModel
+		want string
+	}{
+		{db.SELECT(TestSoft{}), `SELECT  * FROM "test_soft" WHERE deleted_at IS NULL`},
+		{db.SELECT(TestSoft{Name: "a"}).Where(And("name")), `SELECT  * FROM "test_soft" WHERE (name=$1) AND deleted_at IS NULL`},
+		{db.SELECT(TestSoft{}).Unscoped(), `SELECT  * FROM "test_soft"`},
+		{db.UPDATE(TestSoft{ID: 1, Name: "b"}).WherePK(), `UPDATE "test_soft" SET name=$1 WHERE (id=$2) AND deleted_at IS NULL`},
+	}
+	for _, c := range cases {
+		if sp := c.o.BuildSQL(); sp.Sql != c.want {
+			t.Errorf("sql = %s, want %s", sp.Sql, c.want)
+		}
+	}
+
+	f.columns = []string{"count"}
+	f.rows = [][]driver.Value{{int64(3)}}
+	if _, err := db.SELECT(TestSoft{}).Count("*"); err != nil {
+		t.Fatal(err)
+	}
+	if want := `SELECT count(*) FROM "test_soft"  WHERE deleted_at IS NULL`; f.stmts[len(f.stmts)-1] != want {
+		t.Fatalf("sql = %s", f.stmts[len(f.stmts)-1])
+	}
+}
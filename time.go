@@ -0,0 +1,98 @@
+package mworm
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TimeLocation 读取 time.Time 字段时转换到的时区，nil 时保持驱动返回的时区；
+// 驱动以文本返回时间(SQLite、未开启 parseTime 的 MySQL)时按此时区解析，nil 时按 UTC
+var TimeLocation *time.Location
+
+// NowFunc 自动时间字段使用的时钟，测试时可替换为固定时间
+var NowFunc = time.Now
+
+// timeLayout 写入时间字面量的格式
+const timeLayout = "2006-01-02 15:04:05.999999999Z07:00"
+
+// timeLayouts 解析文本时间时依次尝试的格式
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	timeLayout,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// localTime 将 t 转换到 TimeLocation
+func localTime(t time.Time) time.Time {
+	if TimeLocation != nil {
+		return t.In(TimeLocation)
+	}
+	return t
+}
+
+// parseTime 按 timeLayouts 解析文本时间
+func parseTime(s string) (time.Time, error) {
+	loc := TimeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("error: cannot parse %q as time.Time", s)
+}
+
+// isTimeType t 是否为 time.Time 或 *time.Time
+func isTimeType(t reflect.Type) bool {
+	return t == timeType || (t.Kind() == reflect.Ptr && t.Elem() == timeType)
+}
+
+// setTimeValue 将驱动返回的 val 写入 time.Time 或 *time.Time 字段
+func setTimeValue(rv reflect.Value, val any) error {
+	var t time.Time
+	switch v := val.(type) {
+	case time.Time:
+		t = localTime(v)
+	case string:
+		var err error
+		if t, err = parseTime(v); err != nil {
+			return err
+		}
+	case []byte:
+		var err error
+		if t, err = parseTime(string(v)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("error: (%s) type not processed, because value: %v", rv.Type(), val)
+	}
+	if rv.Kind() == reflect.Ptr {
+		rv.Set(reflect.ValueOf(&t))
+		return nil
+	}
+	rv.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// timestampValue 按字段类型 t 返回自动时间字段的值：time.Time、*time.Time，整数为 Unix 秒，字符串按 timeLayout 格式化
+func timestampValue(t reflect.Type, now time.Time) any {
+	if t == nil {
+		return now
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		if t.Elem() == timeType {
+			return &now
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(now.Unix()).Convert(t).Interface()
+	case reflect.String:
+		return now.Format(timeLayout)
+	}
+	return now
+}
@@ -0,0 +1,110 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type TestTimes struct {
+	ID        int        `json:"id" db:"id,pk"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at"`
+	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at"`
+}
+
+func (TestTimes) TableName() string { return "test_times" }
+
+func TestTimeField(t *testing.T) {
+	db, f := newFakeDB(t)
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := db.INSERT(TestTimes{ID: 1, CreatedAt: created}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "test_times" (id, created_at) VALUES ($1, $2)`; f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	if want := []driver.Value{int64(1), created}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+	if s := ValueTypeToStr(created); s != `'2024-05-06 07:08:09Z'` {
+		t.Fatalf("literal = %s", s)
+	}
+
+	loc := time.FixedZone("UTC+8", 8*3600)
+	TimeLocation = loc
+	t.Cleanup(func() { TimeLocation = nil })
+	f.columns = []string{"id", "created_at", "deleted_at"}
+	f.rows = [][]driver.Value{
+		{int64(1), created, nil},
+		{int64(2), []byte("2024-05-06 15:08:09"), "2024-05-07T00:00:00Z"},
+	}
+	list, err := Fetch[TestTimes](db.SELECT(TestTimes{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !list[0].CreatedAt.Equal(created) || list[0].CreatedAt.Location() != loc || list[0].DeletedAt != nil {
+		t.Fatalf("row = %+v", list[0])
+	}
+	if !list[1].CreatedAt.Equal(created) || list[1].DeletedAt == nil ||
+		!list[1].DeletedAt.Equal(time.Date(2024, 5, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("row = %+v", list[1])
+	}
+
+	f.rows = [][]driver.Value{{int64(3), "bad", nil}}
+	if _, err = Fetch[TestTimes](db.SELECT(TestTimes{})); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+type TestStamped struct {
+	ID        int        `json:"id" db:"id,pk"`
+	Name      string     `json:"name" db:"name"`
+	CreatedAt time.Time  `json:"createdAt" db:"created_at,created"`
+	UpdatedAt *time.Time `json:"updatedAt" db:"updated_at,updated"`
+	Touched   int64      `json:"touched" db:"touched,at"`
+}
+
+func (TestStamped) TableName() string { return "test_stamped" }
+
+func TestTimestamps(t *testing.T) {
+	db, f := newFakeDB(t)
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	NowFunc = func() time.Time { return now }
+	t.Cleanup(func() { NowFunc = time.Now })
+
+	if err := db.INSERT(TestStamped{Name: "a"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "test_stamped" (name, created_at, updated_at, touched) VALUES ($1, $2, $3, $4)`; f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	if want := []driver.Value{"a", now, now, now.Unix()}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+
+	created := now.Add(-time.Hour)
+	if err := db.INSERT(TestStamped{Name: "b", CreatedAt: created}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if f.args[1][1] != created {
+		t.Fatalf("args = %#v", f.args[1])
+	}
+
+	if err := db.UPDATE(TestStamped{ID: 1, Name: "c", CreatedAt: created}).WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "test_stamped" SET name=$1, created_at=$2, updated_at=$3, touched=$4 WHERE (id=$5)`; f.stmts[2] != want {
+		t.Fatalf("sql = %s", f.stmts[2])
+	}
+	sp := db.UPDATE(TestStamped{}).SetField("name", "d").ExcludeFields("touched").Where(Eq("id", 1)).BuildSQL()
+	if want := `UPDATE "test_stamped" SET updated_at=$1, name=$2 WHERE id=$3`; sp.Sql != want {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+
+	sp = db.UPSERT(TestStamped{ID: 1, Name: "e"}).BuildSQL()
+	if !strings.HasSuffix(sp.Sql, `DO UPDATE SET name=EXCLUDED.name, updated_at=EXCLUDED.updated_at, touched=EXCLUDED.touched`) {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+}
@@ -0,0 +1,75 @@
+package mworm
+
+import "context"
+
+// Attribute span 属性
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span 一次被追踪的操作，可适配 OpenTelemetry trace.Span
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer 创建 Span，可适配 OpenTelemetry trace.Tracer，ctx 中的父 span 由实现负责关联
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// tracer 为 nil 时不追踪
+var tracer Tracer
+
+// SetTracer 设置 Tracer，Exec/One/Many/Count/PAGE 各开启一个 span，应在初始化时调用
+//
+// span 属性：db.system、db.sql.table、db.operation、db.statement
+func SetTracer(t Tracer) {
+	tracer = t
+}
+
+type spanKey struct{}
+
+// startSpan 开启名为 mworm.name 的 span，执行期间 o 使用带 span 的 ctx，返回结束 span 的函数
+func (o *OrmModel) startSpan(name string) func(err error) {
+	if tracer == nil {
+		return func(error) {}
+	}
+	attrs := []Attribute{
+		{Key: "db.system", Value: dbSystem(o.dialect().Name())},
+		{Key: "db.sql.table", Value: o.table},
+	}
+	if len(o.method) > 0 && !o.rawSQL {
+		attrs = append(attrs, Attribute{Key: "db.operation", Value: o.method})
+	}
+	ctx, span := tracer.Start(o.context(), "mworm."+name, attrs...)
+	prev := o.ctx
+	o.ctx = context.WithValue(ctx, spanKey{}, span)
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+		o.ctx = prev
+	}
+}
+
+// traceStatement 将执行的 SQL 记录到 ctx 中的 span
+func traceStatement(ctx context.Context, stmt *Statement) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(Attribute{Key: "db.operation", Value: stmt.Operation},
+		Attribute{Key: "db.statement", Value: stmt.SQL})
+}
+
+// dbSystem 方言名称对应的 OpenTelemetry db.system
+func dbSystem(name string) string {
+	if name == "postgres" {
+		return "postgresql"
+	}
+	return name
+}
@@ -0,0 +1,85 @@
+package mworm
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]any
+	err    error
+	ended  bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+type spanRecorderKey struct{}
+
+// spanRecorder 内存中的 Tracer，记录全部 span
+type spanRecorder struct {
+	spans []*recordedSpan
+}
+
+func (r *spanRecorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(spanRecorderKey{}).(*recordedSpan)
+	s := &recordedSpan{name: name, parent: parent, attrs: map[string]any{}}
+	s.SetAttributes(attrs...)
+	r.spans = append(r.spans, s)
+	return context.WithValue(ctx, spanRecorderKey{}, s), s
+}
+
+func TestTracing(t *testing.T) {
+	db, f := newFakeDB(t)
+	r := new(spanRecorder)
+	SetTracer(r)
+	t.Cleanup(func() { SetTracer(nil) })
+
+	root := &recordedSpan{name: "request", attrs: map[string]any{}}
+	ctx := context.WithValue(context.Background(), spanRecorderKey{}, root)
+	if err := db.INSERT(TestTable{ID: 1}).Ctx(ctx).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	f.columns = []string{"id"}
+	f.rows = [][]driver.Value{{int64(1)}}
+	var list []TestTable
+	if err := db.SELECT(TestTable{}).Ctx(ctx).Many(&list); err != nil {
+		t.Fatal(err)
+	}
+	f.rows = [][]driver.Value{{int64(1)}}
+	if _, err := db.SELECT(TestTable{}).Ctx(ctx).Count("*"); err != nil {
+		t.Fatal(err)
+	}
+	f.failOn = "DELETE"
+	if err := db.DELETE(TestTable{ID: 1}).WherePK().Exec(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if len(r.spans) != 4 {
+		t.Fatalf("spans = %d", len(r.spans))
+	}
+	s := r.spans[0]
+	if s.name != "mworm.Exec" || s.parent != root || !s.ended || s.attrs["db.system"] != "postgresql" ||
+		s.attrs["db.sql.table"] != "test_table" || s.attrs["db.operation"] != methodInsert ||
+		s.attrs["db.statement"] != f.stmts[0] {
+		t.Fatalf("span = %+v", s)
+	}
+	if s = r.spans[1]; s.name != "mworm.Many" || s.parent != root || s.attrs["db.operation"] != methodSelect {
+		t.Fatalf("span = %+v", s)
+	}
+	if s = r.spans[2]; s.name != "mworm.Count" || s.attrs["db.statement"] != f.stmts[2] {
+		t.Fatalf("span = %+v", s)
+	}
+	if s = r.spans[3]; s.parent != nil || s.err == nil || !s.ended {
+		t.Fatalf("span = %+v", s)
+	}
+}
@@ -0,0 +1,207 @@
+package mworm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tx 事务句柄，提供与 DB 相同的构造方法，由其创建的 OrmModel 在事务内执行
+type Tx struct {
+	sqlxTx    *sqlx.Tx
+	db        *DB
+	ctx       context.Context
+	savepoint string // 嵌套事务的 SAVEPOINT 名称，顶层事务为空
+	seq       *int   // SAVEPOINT 序号，嵌套事务共享
+}
+
+// Sqlx 返回底层 *sqlx.Tx
+func (tx *Tx) Sqlx() *sqlx.Tx {
+	return tx.sqlxTx
+}
+
+// Commit 提交事务，嵌套事务中释放 SAVEPOINT
+func (tx *Tx) Commit() error {
+	if len(tx.savepoint) > 0 {
+		_, err := tx.sqlxTx.ExecContext(tx.ctx, `RELEASE SAVEPOINT `+tx.savepoint)
+		return err
+	}
+	return tx.sqlxTx.Commit()
+}
+
+// Rollback 回滚事务，嵌套事务中回滚到 SAVEPOINT
+func (tx *Tx) Rollback() error {
+	if len(tx.savepoint) > 0 {
+		_, err := tx.sqlxTx.ExecContext(tx.ctx, `ROLLBACK TO SAVEPOINT `+tx.savepoint)
+		return err
+	}
+	return tx.sqlxTx.Rollback()
+}
+
+// Batch 在当前事务中创建 SAVEPOINT 执行 f，f 返回错误时回滚到该 SAVEPOINT，否则释放
+func (tx *Tx) Batch(f func(tx *Tx) error) error {
+	if f == nil {
+		return nil
+	}
+	if tx.seq == nil {
+		tx.seq = new(int)
+	}
+	*tx.seq++
+	inner := &Tx{sqlxTx: tx.sqlxTx, db: tx.db, ctx: tx.ctx, seq: tx.seq,
+		savepoint: fmt.Sprintf(`mworm_sp_%d`, *tx.seq)}
+	if _, err := tx.sqlxTx.ExecContext(tx.ctx, `SAVEPOINT `+inner.savepoint); err != nil {
+		return err
+	}
+	if err := f(inner); err != nil {
+		if rbErr := inner.Rollback(); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return inner.Commit()
+}
+
+// Table 指定表名
+func (tx *Tx) Table(name string) *OrmModel {
+	o := tx.db.Table(name)
+	o.tx, o.ctx = tx, tx.ctx
+	return o
+}
+
+// SELECT 查询
+func (tx *Tx) SELECT(i ORMInterface, distinct ...bool) *OrmModel {
+	return tx.Table(i.TableName()).setMethod(methodSelect, i, distinct...)
+}
+
+// INSERT 插入
+func (tx *Tx) INSERT(i ORMInterface) *OrmModel {
+	return tx.Table(i.TableName()).setMethod(methodInsert, i)
+}
+
+// UPDATE 更新
+func (tx *Tx) UPDATE(i ORMInterface) *OrmModel {
+	return tx.Table(i.TableName()).setMethod(methodUpdate, i)
+}
+
+// DELETE 删除
+func (tx *Tx) DELETE(i ORMInterface) *OrmModel {
+	return tx.Table(i.TableName()).setMethod(methodDelete, i)
+}
+
+// RawSQL 原生 SQL 查询，args 按驱动占位符顺序绑定
+func (tx *Tx) RawSQL(sql string, args ...any) *OrmModel {
+	o := tx.db.RawSQL(sql, args...)
+	o.tx, o.ctx = tx, tx.ctx
+	return o
+}
+
+// RawNamedSQL 带命名参数的原生 SQL
+func (tx *Tx) RawNamedSQL(sql string, params any) *OrmModel {
+	o := tx.db.RawNamedSQL(sql, params)
+	o.tx, o.ctx = tx, tx.ctx
+	return o
+}
+
+// Exec 执行 SQL 语句，影响行数为 0 时返回错误
+func (tx *Tx) Exec(sqlStr string, args ...any) error {
+	return checkAffected(execContext(tx.ctx, tx.ext(), sqlStr, args...))
+}
+
+// NamedExec 执行带命名参数的 SQL 语句
+func (tx *Tx) NamedExec(sqlStr string, params map[string]interface{}) error {
+	return checkAffected(namedExecContext(tx.ctx, tx.ext(), sqlStr, params))
+}
+
+// Query 执行 SQL 查询并将第一行映射到 dest，args 按驱动占位符顺序绑定
+func (tx *Tx) Query(query string, dest any, args ...any) error {
+	return queryContext(tx.ctx, tx.ext(), query, dest, args...)
+}
+
+// NamedQuery 执行带命名参数的 SQL 查询并映射结果
+func (tx *Tx) NamedQuery(query string, params any, dest any) error {
+	fieldMap, _ := StructToMap(params)
+	return namedQueryContext(tx.ctx, tx.ext(), tx.db.Dialect(), query, fieldMap, dest)
+}
+
+// BatchArray 在当前事务中依次执行 ormArray
+func (tx *Tx) BatchArray(ormArray []*OrmModel) error {
+	for _, o := range ormArray {
+		if o == nil {
+			continue
+		}
+		if _, err := o.execInTx(tx, tx.ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execInTx 在 tx 中执行 o，执行后恢复 o 原来的事务与 ctx，使 o 在批量执行后仍可复用
+func (o *OrmModel) execInTx(tx *Tx, ctx context.Context) (int64, error) {
+	prevTx, prevCtx := o.tx, o.ctx
+	defer func() { o.tx, o.ctx = prevTx, prevCtx }()
+	o.tx, o.ctx = tx, ctx
+	return o.execResult()
+}
+
+// executor 返回执行 SQL 的连接，事务中为 *sqlx.Tx，未连接时为 nil
+func (o *OrmModel) executor() sqlx.ExtContext {
+	if o.tx != nil {
+		return o.intercept(o.tx.sqlxTx)
+	}
+	if db := o.database().sqlxDB; db != nil {
+		return o.intercept(db)
+	}
+	return nil
+}
+
+// execResult 执行 INSERT/UPDATE/DELETE 及原生 SQL，返回影响行数
+func (o *OrmModel) execResult() (int64, error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+	e := o.executor()
+	if e == nil {
+		o.err = ErrNilDB
+		return 0, o.err
+	}
+	if o.err = o.beforeHook(); o.err != nil {
+		return 0, o.err
+	}
+	if o.bulkRows != nil {
+		count, err := o.execBulk(e)
+		if err == nil {
+			err = o.afterHook()
+		}
+		o.err = err
+		return count, err
+	}
+	var count int64
+	if o.rawSQL {
+		if len(o.params) > 0 && o.namedExec {
+			count, o.err = namedExecContext(o.context(), e, o.sql, o.params)
+		} else {
+			count, o.err = execContext(o.context(), e, o.sql, o.args...)
+		}
+	} else {
+		sqlParams := o.FullSQL()
+		if sqlParams.Err != nil {
+			return 0, sqlParams.Err
+		}
+		if len(sqlParams.Sql) == 0 {
+			o.err = ErrEmptySQL
+			return 0, o.err
+		}
+		count, o.err = execContext(o.context(), e, sqlParams.Sql, sqlParams.Args...)
+		if o.err == nil && count == 0 && o.lockVersion {
+			// 版本不一致，记录已被其他事务修改
+			o.err = ErrStaleObject
+		}
+	}
+	if o.err == nil {
+		o.err = o.afterHook()
+	}
+	return count, o.err
+}
@@ -0,0 +1,155 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBatchFuncCommit(t *testing.T) {
+	db, f := newFakeDB(t)
+	err := db.BatchFunc(func(tx *Tx) error {
+		if err := tx.INSERT(TestTable{Name: "a"}).Exec(); err != nil {
+			return err
+		}
+		return tx.UPDATE(TestTable{ID: 1, Name: "b"}).WherePK().Exec()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN",
+		`INSERT INTO "test_table" (name) VALUES ($1)`,
+		`UPDATE "test_table" SET name=$1 WHERE (id=$2)`,
+		"COMMIT",
+	}
+	if got := f.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements = %q", got)
+	}
+}
+
+func TestBatchFuncRollback(t *testing.T) {
+	db, f := newFakeDB(t)
+	errAbort := errors.New("abort")
+	err := db.BatchFunc(func(tx *Tx) error {
+		if err := tx.DELETE(TestTable{ID: 1}).WherePK().Exec(); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("err = %v", err)
+	}
+	want := []string{"BEGIN", `DELETE FROM "test_table"  WHERE (id=$1)`, "ROLLBACK"}
+	if got := f.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements = %q", got)
+	}
+}
+
+func TestBatchArrayRollback(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.failOn = "DELETE"
+	err := db.Batch(INSERT(TestTable{Name: "a"}), DELETE(TestTable{ID: 1}).WherePK())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	got := f.statements()
+	if got[len(got)-1] != "ROLLBACK" {
+		t.Fatalf("statements = %q", got)
+	}
+}
+
+func TestNestedBatch(t *testing.T) {
+	db, f := newFakeDB(t)
+	errInner := errors.New("inner")
+	err := db.BatchFunc(func(tx *Tx) error {
+		if err := tx.INSERT(TestTable{Name: "order"}).Exec(); err != nil {
+			return err
+		}
+		if err := tx.Batch(func(inner *Tx) error {
+			if err := inner.UPDATE(TestTable{ID: 1, Name: "stock"}).WherePK().Exec(); err != nil {
+				return err
+			}
+			return errInner
+		}); !errors.Is(err, errInner) {
+			t.Fatalf("inner err = %v", err)
+		}
+		return tx.Batch(func(inner *Tx) error {
+			return inner.Batch(func(deep *Tx) error {
+				return deep.DELETE(TestTable{ID: 2}).WherePK().Exec()
+			})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN",
+		`INSERT INTO "test_table" (name) VALUES ($1)`,
+		`SAVEPOINT mworm_sp_1`,
+		`UPDATE "test_table" SET name=$1 WHERE (id=$2)`,
+		`ROLLBACK TO SAVEPOINT mworm_sp_1`,
+		`SAVEPOINT mworm_sp_2`,
+		`SAVEPOINT mworm_sp_3`,
+		`DELETE FROM "test_table"  WHERE (id=$1)`,
+		`RELEASE SAVEPOINT mworm_sp_3`,
+		`RELEASE SAVEPOINT mworm_sp_2`,
+		"COMMIT",
+	}
+	if got := f.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements = %q", got)
+	}
+}
+
+func TestBatchRestoresBuilder(t *testing.T) {
+	db, f := newFakeDB(t)
+	o := db.INSERT(TestTable{Name: "a"})
+	if err := db.Batch(o); err != nil {
+		t.Fatal(err)
+	}
+	err := db.BatchFunc(func(tx *Tx) error {
+		return tx.BatchArray([]*OrmModel{o})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.tx != nil || o.ctx != nil {
+		t.Fatalf("builder still bound to tx %v, ctx %v", o.tx, o.ctx)
+	}
+	// 批量执行后复用构造器，不再使用已提交的事务
+	if err = o.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	insert := `INSERT INTO "test_table" (name) VALUES ($1)`
+	want := []string{"BEGIN", insert, "COMMIT", "BEGIN", insert, "COMMIT", insert}
+	if got := f.statements(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements = %q", got)
+	}
+}
+
+func TestTxPAGE(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.columns = []string{"list", "total"}
+	f.rows = [][]driver.Value{{[]byte(`[{"id":1,"name":"a"},{"id":2,"name":"b"}]`), int64(3)}}
+	var result PageResult[TestTable]
+	err := db.BatchFunc(func(tx *Tx) error {
+		return tx.PAGE(TestTable{}, 1, 2, nil, &result, Eq("type", 1))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || result.TotalPage != 2 || len(result.List) != 2 || result.List[1].Name != "b" {
+		t.Fatalf("result = %+v", result)
+	}
+	got := f.statements()
+	if len(got) != 3 || got[0] != "BEGIN" || !strings.Contains(got[1], `WHERE type=$1`) || got[2] != "COMMIT" {
+		t.Fatalf("statements = %q", got)
+	}
+	if err = db.BatchFunc(func(tx *Tx) error {
+		return tx.PAGE(TestTable{}, 1, 2, nil, &[]TestTable{})
+	}); err == nil {
+		t.Fatal("invalid dest should return error")
+	}
+}
@@ -0,0 +1,116 @@
+package mworm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// upsertClause UPSERT 的冲突处理配置，字段均为 json tag
+type upsertClause struct {
+	conflict  []string // 冲突字段，为空时使用 pk
+	update    []string // 冲突时更新的字段，为空时更新除冲突字段外的全部插入字段
+	doNothing bool     // 冲突时忽略
+}
+
+// UPSERT 插入，冲突时更新
+func UPSERT(i ORMInterface) *OrmModel {
+	return defaultDB().UPSERT(i)
+}
+
+// UPSERT 插入，冲突时更新，postgres/SQLite 生成 ON CONFLICT，MySQL 生成 ON DUPLICATE KEY UPDATE
+func (d *DB) UPSERT(i ORMInterface) *OrmModel {
+	o := d.INSERT(i)
+	o.upsert = new(upsertClause)
+	return o
+}
+
+// UPSERT 在事务中插入，冲突时更新
+func (tx *Tx) UPSERT(i ORMInterface) *OrmModel {
+	o := tx.INSERT(i)
+	o.upsert = new(upsertClause)
+	return o
+}
+
+// OnConflict 指定冲突字段，默认使用 pk，MySQL 按表的唯一索引判断冲突
+func (o *OrmModel) OnConflict(jsonTag ...string) *OrmModel {
+	o.upsertClause().conflict = jsonTag
+	return o
+}
+
+// DoUpdate 指定冲突时更新的字段
+func (o *OrmModel) DoUpdate(jsonTag ...string) *OrmModel {
+	u := o.upsertClause()
+	u.update, u.doNothing = jsonTag, false
+	return o
+}
+
+// DoNothing 冲突时忽略
+func (o *OrmModel) DoNothing() *OrmModel {
+	u := o.upsertClause()
+	u.update, u.doNothing = nil, true
+	return o
+}
+
+func (o *OrmModel) upsertClause() *upsertClause {
+	if o.upsert == nil {
+		o.upsert = new(upsertClause)
+	}
+	return o.upsert
+}
+
+// upsertSQL 冲突处理子句，fieldArr 为插入的列
+func (o *OrmModel) upsertSQL(fieldArr []string) string {
+	if o.upsert == nil {
+		return ""
+	}
+	conflictTags := o.upsert.conflict
+	if len(conflictTags) == 0 && len(o.pk) > 0 {
+		conflictTags = []string{o.pk}
+	}
+	conflict := o.upsertColumns(conflictTags)
+	var update []string
+	if !o.upsert.doNothing {
+		if len(o.upsert.update) > 0 {
+			update = o.upsertColumns(o.upsert.update)
+		} else {
+			skip := make(map[string]emptyKey, len(conflict))
+			for _, c := range conflict {
+				skip[c] = emptyKey{}
+			}
+			// 冲突时保留创建时间
+			for c := range o.createdFields {
+				skip[c] = emptyKey{}
+			}
+			for _, f := range fieldArr {
+				if _, ok := skip[f]; !ok {
+					update = append(update, f)
+				}
+			}
+		}
+	}
+	mysql := o.dialect().Name() == "mysql"
+	if len(conflict) == 0 {
+		switch {
+		case len(update) > 0 && !mysql:
+			o.err = errors.New("upsert DO UPDATE requires a conflict target, add a pk field or call OnConflict")
+			return ""
+		case len(update) == 0 && mysql && len(fieldArr) > 0:
+			// MySQL 按唯一索引判断冲突，冲突列只用于生成 col=col 的空更新
+			conflict = fieldArr[:1]
+		}
+	}
+	return o.dialect().Upsert(conflict, update)
+}
+
+func (o *OrmModel) upsertColumns(jsonTags []string) []string {
+	columns := make([]string, 0, len(jsonTags))
+	for _, j := range jsonTags {
+		column := o.columnField(j)
+		if len(column) == 0 {
+			o.err = fmt.Errorf("UPSERT: unknown field %s", j)
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
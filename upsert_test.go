@@ -0,0 +1,103 @@
+package mworm
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestUPSERT(t *testing.T) {
+	pg := NewDB(sqlx.NewDb(nil, "postgres"))
+	my := NewDB(sqlx.NewDb(nil, "mysql"))
+
+	cases := []struct {
+		orm  *OrmModel
+		want string
+	}{
+		{pg.UPSERT(TestTable{ID: 1, Name: "a", Type: 2}),
+			`INSERT INTO "test_table" (id, name, type) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET name=EXCLUDED.name, type=EXCLUDED.type`},
+		{pg.UPSERT(TestTable{ID: 1, Name: "a", Type: 2}).OnConflict("name").DoUpdate("type"),
+			`INSERT INTO "test_table" (id, name, type) VALUES ($1, $2, $3) ON CONFLICT (name) DO UPDATE SET type=EXCLUDED.type`},
+		{pg.UPSERT(TestTable{ID: 1, Name: "a"}).DoNothing(),
+			`INSERT INTO "test_table" (id, name) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`},
+		{my.UPSERT(TestTable{ID: 1, Name: "a", Type: 2}),
+			"INSERT INTO `test_table` (id, name, type) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE name=VALUES(name), type=VALUES(type)"},
+		{my.UPSERT(TestTable{ID: 1, Name: "a"}).DoNothing(),
+			"INSERT INTO `test_table` (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE id=id"},
+	}
+	for _, c := range cases {
+		if sp := c.orm.BuildSQL(); sp.Sql != c.want {
+			t.Errorf("sql = %s", sp.Sql)
+		}
+	}
+
+	sqls, err := pg.INSERTMany([]TestTable{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}).DoNothing().BuildBulkSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "test_table" (id, name) VALUES ($1, $2), ($3, $4) ON CONFLICT (id) DO NOTHING`; sqls[0].Sql != want {
+		t.Fatalf("bulk sql = %s", sqls[0].Sql)
+	}
+
+	if sp := pg.UPSERT(TestTable{ID: 1}).OnConflict("missing").BuildSQL(); sp.Err == nil {
+		t.Fatal("unknown conflict field should return error")
+	}
+}
+
+func TestUPSERTNoAffected(t *testing.T) {
+	db, f := newFakeDB(t)
+	f.affected = 0
+	// 只插入冲突字段时生成 DO NOTHING
+	if err := db.UPSERT(TestTable{ID: 1}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `INSERT INTO "test_table" (id) VALUES ($1) ON CONFLICT (id) DO NOTHING`; f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	// MySQL 更新后值未变时影响行数为 0
+	db.SetDialect(MySQLDialect{})
+	if err := db.UPSERT(TestTable{ID: 1, Name: "a"}).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "INSERT INTO `test_table` (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name=VALUES(name)"; f.stmts[1] != want {
+		t.Fatalf("sql = %s", f.stmts[1])
+	}
+	if err := db.INSERT(TestTable{ID: 1, Name: "a"}).Exec(); err == nil {
+		t.Fatal("INSERT affecting 0 rows should return error")
+	}
+}
+
+type TestNoPK struct {
+	Email string `json:"email" db:"email"`
+	Name  string `json:"name" db:"name"`
+}
+
+func (TestNoPK) TableName() string { return "test_no_pk" }
+
+func TestUPSERTNoConflictTarget(t *testing.T) {
+	pg := NewDB(sqlx.NewDb(nil, "postgres"))
+	my := NewDB(sqlx.NewDb(nil, "mysql"))
+	row := TestNoPK{Email: "a@x.com", Name: "a"}
+
+	// DO UPDATE 需要冲突字段
+	if sp := pg.UPSERT(row).BuildSQL(); sp.Err == nil {
+		t.Fatalf("sql = %s, expected error", sp.Sql)
+	}
+	if _, err := pg.INSERTMany([]TestNoPK{row}).DoUpdate("name").BuildBulkSQL(); err == nil {
+		t.Fatal("bulk DO UPDATE without conflict target should return error")
+	}
+	if sp := pg.UPSERT(row).OnConflict("email").BuildSQL(); sp.Err != nil {
+		t.Fatal(sp.Err)
+	}
+	if sp := pg.UPSERT(row).DoNothing().BuildSQL(); sp.Sql != `INSERT INTO "test_no_pk" (email, name) VALUES ($1, $2) ON CONFLICT DO NOTHING` {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+
+	// MySQL 按唯一索引判断冲突，不需要冲突字段
+	if sp := my.UPSERT(row).BuildSQL(); sp.Sql != "INSERT INTO `test_no_pk` (email, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE email=VALUES(email), name=VALUES(name)" {
+		t.Fatalf("sql = %s, err = %v", sp.Sql, sp.Err)
+	}
+	if sp := my.UPSERT(row).DoNothing().BuildSQL(); sp.Sql != "INSERT INTO `test_no_pk` (email, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE email=email" {
+		t.Fatalf("sql = %s, err = %v", sp.Sql, sp.Err)
+	}
+}
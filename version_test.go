@@ -0,0 +1,64 @@
+package mworm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type TestVersioned struct {
+	ID      int    `json:"id" db:"id,pk"`
+	Name    string `json:"name" db:"name"`
+	Version int    `json:"version" db:"version,version"`
+}
+
+func (TestVersioned) TableName() string { return "test_versioned" }
+
+func TestOptimisticLock(t *testing.T) {
+	db, f := newFakeDB(t)
+	row := TestVersioned{ID: 1, Name: "a", Version: 3}
+	if err := db.UPDATE(row).WherePK().Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if want := `UPDATE "test_versioned" SET name=$1, version=version+1 WHERE (id=$2) AND (version=$3)`; f.stmts[0] != want {
+		t.Fatalf("sql = %s", f.stmts[0])
+	}
+	if want := []driver.Value{"a", int64(1), int64(3)}; !reflect.DeepEqual(f.args[0], want) {
+		t.Fatalf("args = %#v", f.args[0])
+	}
+
+	f.affected = 0
+	err := db.UPDATE(row).WherePK().Exec()
+	var e *Error
+	if !errors.Is(err, ErrStaleObject) || !errors.As(err, &e) || e.Code != 1006 {
+		t.Fatalf("err = %v", err)
+	}
+	err = db.BatchFunc(func(tx *Tx) error {
+		return tx.BatchArray([]*OrmModel{tx.UPDATE(row).WherePK()})
+	})
+	if !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("batch err = %v", err)
+	}
+
+	// RETURNING 没有返回记录时同样视为版本不一致
+	var got TestVersioned
+	if err = db.UPDATE(row).WherePK().RETURNING(&got, nil); !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("RETURNING single err = %v", err)
+	}
+	var list []TestVersioned
+	if err = db.UPDATE(row).WherePK().RETURNING(nil, &list); !errors.Is(err, ErrStaleObject) {
+		t.Fatalf("RETURNING list err = %v", err)
+	}
+	f.columns = []string{"id", "version"}
+	f.rows = [][]driver.Value{{int64(1), int64(4)}}
+	if err = db.UPDATE(row).WherePK().RETURNING(&got, nil, "id", "version"); err != nil || got.Version != 4 {
+		t.Fatalf("RETURNING = %+v, err = %v", got, err)
+	}
+
+	// 不使用 WherePK 时不校验版本
+	sp := db.UPDATE(row).Where(Eq("name", "a")).BuildSQL()
+	if want := `UPDATE "test_versioned" SET id=$1, name=$2, version=$3 WHERE name=$4`; sp.Sql != want {
+		t.Fatalf("sql = %s", sp.Sql)
+	}
+}